@@ -2,37 +2,66 @@ package main
 
 import (
 	"bufio"
-	"bytes"
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"log"
-	"math"
 	"net/http"
 	"os"
+	"os/signal"
+	"slices"
+	"strings"
 	"sync"
+	"syscall"
 	"time"
 
 	_ "github.com/go-sql-driver/mysql"
 	_ "github.com/joho/godotenv/autoload"
-	"github.com/zaelmyth/book-data-collector/google"
+	_ "github.com/lib/pq"
+	_ "github.com/zaelmyth/book-data-collector/google"
+	"github.com/zaelmyth/book-data-collector/internal/batcher"
+	"github.com/zaelmyth/book-data-collector/internal/client"
 	"github.com/zaelmyth/book-data-collector/internal/configuration"
 	"github.com/zaelmyth/book-data-collector/internal/db"
-	"github.com/zaelmyth/book-data-collector/isbndb"
+	"github.com/zaelmyth/book-data-collector/internal/input"
+	"github.com/zaelmyth/book-data-collector/internal/isbn"
+	"github.com/zaelmyth/book-data-collector/internal/provider"
+	_ "github.com/zaelmyth/book-data-collector/isbndb"
+	_ "github.com/zaelmyth/book-data-collector/openlibrary"
+	_ "modernc.org/sqlite"
 )
 
 const searchRetryLimit = 3 // todo: make configurable
+const searchGoroutinesPerCall = 5
+
+// isbnBatchIdleTimeout is how long a partial batch of isbns waits for more isbns before it is searched
+const isbnBatchIdleTimeout = 5 * time.Second
+
+// rememberedIsbns is how many of the last isbns are remembered to skip duplicates in the input
+const rememberedIsbns = 1000000
+
+const progressPrintInterval = 200 * time.Millisecond
+
+// savedBooksPerBatch is how many ids of saved books are read from the database at once when refreshing
+const savedBooksPerBatch = 1000
+
+var errInterrupted = errors.New("interrupted")
 
 type searchQuery struct {
 	query string
 	page  int
 	isbns []string
+	// ids are the ids of saved books that are refreshed
+	ids []string
 }
 
 type booksSave struct {
-	books            []isbndb.Book
-	volumes          []google.Volume
+	records          []provider.Record
 	word             string
+	isbns            []string
+	page             int
+	total            int
 	isSearchComplete bool
 }
 
@@ -44,8 +73,6 @@ func main() {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	db.CreateDatabases(ctx, config)
-
 	booksDb := db.GetBooksDatabase(config)
 	defer func(db *sql.DB) {
 		err := db.Close()
@@ -62,22 +89,27 @@ func main() {
 		}
 	}(progressDb)
 
-	db.CreateProgressTables(ctx, progressDb)
-	db.CreateBookTables(ctx, booksDb)
+	// the databases are created and kept up to date by the migrate utility, the collector stops until it was run
+	db.CheckSchemaVersion(ctx, progressDb, db.ProgressSchema)
+	db.CheckSchemaVersion(ctx, booksDb, db.BooksSchema)
 
-	saveBookData(config, ctx, booksDb, progressDb)
+	saveBookData(config, provider.Get(config), ctx, booksDb, progressDb)
 }
 
-func saveBookData(config configuration.Config, ctx context.Context, booksDb *sql.DB, progressDb *sql.DB) {
-	file, err := os.ReadFile(config.File)
-	if err != nil {
-		log.Fatal(err)
-	}
-
+func saveBookData(config configuration.Config, bookProvider provider.Provider, ctx context.Context, booksDb *sql.DB, progressDb *sql.DB) {
 	queries := make(chan searchQuery, 10)
 	defer close(queries)
 
-	priorityQueries := make(chan searchQuery, 100)
+	callsPerSecond := config.CallsPerSecond
+	if callsPerSecond == 0 {
+		callsPerSecond = bookProvider.MaxCallsPerSecond()
+	}
+
+	// calls can take a few seconds so more goroutines than calls per second are needed to use the whole rate
+	searchGoroutines := callsPerSecond * searchGoroutinesPerCall
+
+	// every search goroutine queues at most one next page per query so there is room for all of them
+	priorityQueries := make(chan searchQuery, searchGoroutines)
 	defer close(priorityQueries)
 
 	booksToSave := make(chan booksSave, 10)
@@ -85,104 +117,264 @@ func saveBookData(config configuration.Config, ctx context.Context, booksDb *sql
 
 	var wg sync.WaitGroup
 
-	go searchGoroutine(&wg, config, queries, priorityQueries, booksToSave, ctx, progressDb)
+	client.SetLimiter(client.NewLimiter(callsPerSecond, time.Duration(config.TimeoutSeconds)*time.Second))
+
+	maxCallsPerDay := config.MaxCallsPerDay
+	if maxCallsPerDay == 0 {
+		maxCallsPerDay = bookProvider.MaxCallsPerDay()
+	}
+	if maxCallsPerDay > 0 {
+		quotaStore := apiCallsStore{ctx: ctx, progressDb: progressDb, provider: config.Provider}
+		client.SetQuota(client.NewQuota(maxCallsPerDay, config.OnQuotaExceeded == "wait", quotaStore))
+	}
 
-	bookColumnId := "isbn13"
-	if config.Provider == "google" {
-		bookColumnId = "google_id"
+	if config.ArchiveResponses {
+		client.SetArchive(db.NewResponseArchive(ctx, progressDb, config.Provider))
 	}
 
+	// searching is stopped without stopping the program so that the results that were already fetched are saved
+	searchCtx, stopSearching := context.WithCancelCause(ctx)
+	defer stopSearching(nil)
+
+	go handleSignals(stopSearching)
+
 	savedData := db.SavedData{
-		Books:           db.GetSavedData(ctx, booksDb, "books", bookColumnId),
-		BooksMutex:      &sync.RWMutex{},
-		Authors:         db.GetSavedDataWithId(ctx, booksDb, "authors", "name"),
-		AuthorsMutex:    &sync.Mutex{},
-		Subjects:        db.GetSavedDataWithId(ctx, booksDb, "subjects", "name"),
-		SubjectsMutex:   &sync.Mutex{},
-		Publishers:      db.GetSavedDataWithId(ctx, booksDb, "publishers", "name"),
-		PublishersMutex: &sync.Mutex{},
-		Languages:       db.GetSavedDataWithId(ctx, booksDb, "languages", "name"),
-		LanguagesMutex:  &sync.Mutex{},
-		Queries:         db.GetSavedData(ctx, progressDb, "searched_queries", "query"),
-		QueriesMutex:    &sync.RWMutex{},
+		Books:              db.GetSavedData(ctx, booksDb, "books", bookProvider.IdColumn()),
+		SavingBooks:        make(map[string]struct{}),
+		BooksMutex:         &sync.RWMutex{},
+		Authors:            db.GetSavedDataWithId(ctx, booksDb, "authors", "name_key"),
+		AuthorsMutex:       &sync.Mutex{},
+		AuthorAliases:      db.GetSavedData(ctx, booksDb, "author_aliases", "alias"),
+		AuthorAliasesMutex: &sync.Mutex{},
+		Subjects:           db.GetSavedDataWithId(ctx, booksDb, "subjects", "name"),
+		SubjectsMutex:      &sync.Mutex{},
+		Publishers:         db.GetSavedDataWithId(ctx, booksDb, "publishers", "name"),
+		PublishersMutex:    &sync.Mutex{},
+		Languages:          db.GetSavedDataWithId(ctx, booksDb, "languages", "name"),
+		LanguagesMutex:     &sync.Mutex{},
+		Queries:            db.GetSavedData(ctx, progressDb, "searched_queries", "query"),
+		QueriesMutex:       &sync.RWMutex{},
+		QueryPages:         db.GetQueryProgress(ctx, progressDb),
+		QueryPagesMutex:    &sync.RWMutex{},
+		Isbns:              db.GetSearchedIsbns(ctx, progressDb, getRecheckNotFoundAfter(config)),
+		IsbnsMutex:         &sync.RWMutex{},
 	}
+	for range searchGoroutines {
+		go searchGoroutine(&wg, bookProvider, queries, priorityQueries, booksToSave, searchCtx, stopSearching, ctx, progressDb, savedData)
+	}
+
 	for range config.DbConcurrentWriteGoroutines {
-		go saveGoroutine(&wg, booksToSave, ctx, booksDb, progressDb, savedData)
+		// watched books are saved again like refreshed books so that the prices of the saved ones are kept too
+		go saveGoroutine(&wg, booksToSave, ctx, booksDb, progressDb, bookProvider.IdColumn(), config.Refresh || config.WatchPrices, savedData)
 	}
 
-	linesCount := getLinesCount(file)
+	isbnBatcher := batcher.New(bookProvider.IsbnBatchSize(), isbnBatchIdleTimeout, rememberedIsbns, func(isbns []string) {
+		queueQuery(&wg, queries, searchCtx, searchQuery{
+			page:  1,
+			isbns: isbns,
+		})
+	})
+
+	if config.Refresh {
+		queueSavedBooks(&wg, bookProvider, queries, searchCtx, ctx, booksDb)
+	} else {
+		queueInputQueries(config, bookProvider, &wg, queries, searchCtx, ctx, progressDb, savedData, isbnBatcher)
+	}
 
-	reader := bytes.NewReader(file)
-	scanner := bufio.NewScanner(reader)
+	isbnBatcher.Close()
+
+	fmt.Println("Waiting for remaining data to be saved to the database...")
+	wg.Wait()
+
+	var quotaExceededError *client.QuotaExceededError
+	if errors.As(context.Cause(searchCtx), &quotaExceededError) {
+		fmt.Printf("Daily call quota used up! Run again after %v to continue.\n", quotaExceededError.ResetAt.Local().Format(time.DateTime))
+		return
+	}
+
+	if errors.Is(context.Cause(searchCtx), errInterrupted) {
+		fmt.Println("Stopped! Run again to continue.")
+		return
+	}
+
+	fmt.Println("Done!")
+}
+
+// queueInputQueries queues the queries of the input file
+func queueInputQueries(
+	config configuration.Config,
+	bookProvider provider.Provider,
+	wg *sync.WaitGroup,
+	queries chan searchQuery,
+	searchCtx context.Context,
+	ctx context.Context,
+	progressDb *sql.DB,
+	savedData db.SavedData,
+	isbnBatcher *batcher.Batcher,
+) {
+	queriesInput, err := input.Open(config.File)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer func(queriesInput *input.Input) {
+		err := queriesInput.Close()
+		if err != nil {
+			log.Fatal(err)
+		}
+	}(queriesInput)
+
+	scanner := bufio.NewScanner(queriesInput)
 	progressCount := 0
-	var isbns []string
-	for scanner.Scan() {
-		query := scanner.Text()
+	lastProgressPrint := time.Time{}
+	for scanner.Scan() && searchCtx.Err() == nil {
+		query := strings.TrimSpace(scanner.Text())
+		progressCount++
+
+		if query == "" {
+			continue
+		}
 
 		querySaved := savedData.IsQuerySaved(query)
 		if !querySaved {
-			if config.Provider == "isbndb" && config.SearchBy == "isbn" {
-				// todo: invalid isbns get retried every time so we should probably save what isbns we have already tried
-				isbns = append(isbns, query)
-				if len(isbns) == 1000 {
-					queries <- searchQuery{
-						page:  1,
-						isbns: isbns,
-					}
-					isbns = nil
-					wg.Add(1)
+			if config.SearchBy == "isbn" {
+				// isbns are searched by their isbn-13 so that the same book is never searched twice, invalid ones are skipped
+				isbn13, isValid := isbn.To13(query)
+
+				// watched isbns are searched on every run to get their current prices
+				if isValid && (config.WatchPrices || !savedData.IsIsbnSearched(isbn13)) {
+					isbnBatcher.Add(isbn13)
 				}
 			} else {
-				queries <- searchQuery{
-					query: query,
-					page:  1,
+				page, isComplete := getStartPage(bookProvider, query, savedData)
+				if isComplete {
+					savedData.SaveQuery(ctx, progressDb, query)
+				} else {
+					queueQuery(wg, queries, searchCtx, searchQuery{
+						query: query,
+						page:  page,
+					})
 				}
-				wg.Add(1)
 			}
 		}
 
-		if config.Provider == "isbndb" && config.SearchBy == "isbn" && len(isbns) > 0 {
-			queries <- searchQuery{
-				page:  1,
-				isbns: isbns,
-			}
-			wg.Add(1)
+		// printing every line slows down reading big files
+		if time.Since(lastProgressPrint) > progressPrintInterval {
+			printProgress(progressCount, queriesInput)
+			lastProgressPrint = time.Now()
 		}
-
-		progressCount++
-		progress := int(float64(progressCount) / float64(linesCount) * 100)
-		fmt.Print("\033[H\033[2J") // clear console
-		fmt.Printf("Collecting... %v / %v | %v%%\n", progressCount, linesCount, progress)
 	}
 
+	printProgress(progressCount, queriesInput)
+
 	err = scanner.Err()
 	if err != nil {
 		log.Fatal(err)
 	}
+}
 
-	fmt.Println("Waiting for remaining data to be saved to the database...")
-	wg.Wait()
-	fmt.Println("Done!")
+// queueSavedBooks queues the saved books by their ids so that they are refreshed, an isbn search could return other
+// books than the saved ones
+func queueSavedBooks(
+	wg *sync.WaitGroup,
+	bookProvider provider.Provider,
+	queries chan searchQuery,
+	searchCtx context.Context,
+	ctx context.Context,
+	booksDb *sql.DB,
+) {
+	totalBooks := db.CountBookIds(ctx, booksDb, bookProvider.IdColumn())
+
+	lastId := 0
+	booksCount := 0
+	lastProgressPrint := time.Time{}
+	for searchCtx.Err() == nil {
+		var ids []string
+		ids, lastId = db.GetBookIds(ctx, booksDb, bookProvider.IdColumn(), lastId, savedBooksPerBatch)
+		if len(ids) == 0 {
+			break
+		}
+
+		for batch := range slices.Chunk(ids, bookProvider.IsbnBatchSize()) {
+			queueQuery(wg, queries, searchCtx, searchQuery{
+				page: 1,
+				ids:  batch,
+			})
+
+			booksCount += len(batch)
+			if time.Since(lastProgressPrint) > progressPrintInterval {
+				fmt.Print("\033[H\033[2J") // clear console
+				fmt.Printf("Refreshing... %v / %v books\n", booksCount, totalBooks)
+				lastProgressPrint = time.Now()
+			}
+		}
+	}
+
+	fmt.Print("\033[H\033[2J") // clear console
+	fmt.Printf("Refreshing... %v / %v books\n", booksCount, totalBooks)
+}
+
+// handleSignals stops searching on the first signal so that the data that was already fetched can be saved, the second
+// signal exits right away
+func handleSignals(stopSearching context.CancelCauseFunc) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	<-signals
+	stopSearching(errInterrupted)
+	fmt.Println("Stopping... Waiting for fetched data to be saved to the database. Interrupt again to exit right away.")
+
+	<-signals
+	fmt.Println("Exiting without waiting!")
+	os.Exit(1)
+}
+
+// getStartPage continues queries that were interrupted from the page after the last one that was saved
+func getStartPage(bookProvider provider.Provider, query string, savedData db.SavedData) (int, bool) {
+	progress, hasProgress := savedData.GetQueryProgress(query)
+	if !hasProgress {
+		return 1, false
+	}
+
+	nextPage, hasNextPage := bookProvider.NextPage(provider.Page{Number: progress.LastPage, Total: progress.Total})
+
+	return nextPage, !hasNextPage
+}
+
+// queueQuery gives up if searching was stopped while waiting for space in the queue
+func queueQuery(wg *sync.WaitGroup, queries chan searchQuery, searchCtx context.Context, query searchQuery) {
+	wg.Add(1) // added before queueing because the query can be done before the send returns
+
+	select {
+	case queries <- query:
+	case <-searchCtx.Done():
+		wg.Done()
+	}
 }
 
 func searchGoroutine(
 	wg *sync.WaitGroup,
-	config configuration.Config,
+	bookProvider provider.Provider,
 	queries chan searchQuery,
 	priorityQueries chan searchQuery,
 	booksToSave chan booksSave,
+	searchCtx context.Context,
+	stopSearching context.CancelCauseFunc,
 	ctx context.Context,
 	progressDb *sql.DB,
+	savedData db.SavedData,
 ) {
-	timeoutLimiter := make(chan struct{}, 100) //todo: refactor limiter to a mutex
 	for {
-		if len(timeoutLimiter) == 0 && len(booksToSave) < cap(booksToSave) {
-			for range config.CallsPerSecond {
-				go search(wg, config, timeoutLimiter, queries, priorityQueries, booksToSave, ctx, progressDb)
-			}
+		query, ok := getNextQuery(priorityQueries, queries, searchCtx)
+		if !ok {
+			return
+		}
+
+		if searchCtx.Err() != nil {
+			dropQuery(wg)
+			continue
 		}
 
-		time.Sleep(time.Second)
+		search(wg, bookProvider, query, queries, priorityQueries, booksToSave, searchCtx, stopSearching, ctx, progressDb, savedData)
 	}
 }
 
@@ -192,18 +384,23 @@ func saveGoroutine(
 	ctx context.Context,
 	booksDb *sql.DB,
 	progressDb *sql.DB,
+	idColumn string,
+	isRefresh bool,
 	savedData db.SavedData,
 ) {
 	for booksSave := range booksToSave {
-		for _, book := range booksSave.books {
-			db.SaveBook(ctx, booksDb, book, savedData)
-		}
-		for _, volume := range booksSave.volumes {
-			db.SaveVolume(ctx, booksDb, volume, savedData)
+		db.SaveBooks(ctx, booksDb, idColumn, booksSave.records, savedData, isRefresh)
+
+		if booksSave.isbns != nil {
+			found, notFound := getFoundIsbns(booksSave.isbns, booksSave.records)
+			savedData.SaveIsbns(ctx, progressDb, found, db.IsbnFound)
+			savedData.SaveIsbns(ctx, progressDb, notFound, db.IsbnNotFound)
 		}
 
 		if booksSave.isSearchComplete {
 			savedData.SaveQuery(ctx, progressDb, booksSave.word)
+		} else if booksSave.page > 0 {
+			savedData.SaveQueryPage(ctx, progressDb, booksSave.word, booksSave.total, booksSave.page)
 		}
 
 		wg.Done()
@@ -212,175 +409,257 @@ func saveGoroutine(
 
 func search(
 	wg *sync.WaitGroup,
-	config configuration.Config,
-	timeoutLimiter chan struct{},
+	bookProvider provider.Provider,
+	query searchQuery,
 	queries chan searchQuery,
 	priorityQueries chan searchQuery,
 	booksToSave chan booksSave,
+	searchCtx context.Context,
+	stopSearching context.CancelCauseFunc,
 	ctx context.Context,
 	progressDb *sql.DB,
+	savedData db.SavedData,
 ) {
-	// todo: refactor this
-
-	query, ok := getNextQuery(priorityQueries, queries)
-	if !ok {
-		return
-	}
-
 	for range searchRetryLimit {
-		if config.Provider == "google" {
-			if config.SearchBy == "title" {
-				config.SearchBy = "intitle"
-			}
-			results, statusCode := google.Search(config.SearchBy+":"+query.query, google.SearchParameters{
-				Filter:     "full",
-				StartIndex: (query.page - 1) * google.MaxPageSize,
-				MaxResults: google.MaxPageSize,
-				PrintType:  "books",
-				Projection: "full",
-			})
-
-			if shouldTimeout(statusCode) {
-				handleTimeout(timeoutLimiter, config)
-				continue
-			}
-
-			if len(results.Items) == 0 {
-				handleNoResults(wg, progressDb, ctx, query)
-				return
-			}
-
-			isComplete := false
-			if config.SearchBy != "isbn" {
-				isComplete = isSearchComplete(wg, results.TotalItems, google.MaxPageSize, query, priorityQueries)
-			}
+		var page provider.Page
+		var err error
+		if query.ids != nil {
+			page, err = bookProvider.SearchById(searchCtx, query.ids)
+		} else if query.isbns != nil {
+			page, err = bookProvider.SearchByIsbn(searchCtx, query.isbns)
+		} else {
+			page, err = bookProvider.Search(searchCtx, query.query, query.page)
+		}
 
-			booksToSave <- booksSave{
-				volumes:          results.Items,
-				word:             query.query,
-				isSearchComplete: isComplete,
-			}
+		var quotaExceededError *client.QuotaExceededError
+		if errors.As(err, &quotaExceededError) {
+			stopSearching(err)
+		}
 
+		// searching was stopped while the call was waiting to be made
+		if searchCtx.Err() != nil && (err != nil || len(page.Records) == 0) {
+			dropQuery(wg)
 			return
 		}
 
-		if config.SearchBy == "title" || config.SearchBy == "subject" {
-			if config.SearchBy == "subject" {
-				config.SearchBy = "subjects"
-			}
-			results, statusCode := isbndb.SearchBooksByQuery(isbndb.BookSearchByQueryRequest{
-				Query:    query.query,
-				Page:     query.page,
-				PageSize: isbndb.MaxPageSize,
-				Column:   config.SearchBy,
-			})
+		if err != nil {
+			log.Println(err)
 
-			if shouldTimeout(statusCode) {
-				handleTimeout(timeoutLimiter, config)
+			// the client has already slowed down the calls if the api throttled
+			if shouldRetry(err) {
 				continue
 			}
 
-			if len(results.Books) == 0 {
-				handleNoResults(wg, progressDb, ctx, query)
-				return
-			}
-
-			isSearchComplete := isSearchComplete(wg, results.Total, isbndb.MaxPageSize, query, priorityQueries)
-
-			booksToSave <- booksSave{
-				books:            results.Books,
-				word:             query.query,
-				isSearchComplete: isSearchComplete,
+			if shouldStop(err) {
+				log.Fatal("The api refused the request, check the configuration")
 			}
 
+			// the query isn't saved as searched so it will be tried again on the next run
+			handleFailedSearch(wg, query, ctx, progressDb, savedData)
 			return
 		}
 
-		results, statusCode := isbndb.SearchBooksByIsbn(query.isbns)
-
-		if shouldTimeout(statusCode) {
-			handleTimeout(timeoutLimiter, config)
-			continue
+		if len(page.Records) == 0 {
+			handleNoResults(wg, query, ctx, progressDb, savedData)
+			return
 		}
 
-		if len(results.Data) == 0 {
-			handleNoResults(wg, progressDb, ctx, query)
-			return
+		save := booksSave{
+			records: page.Records,
+			word:    query.query,
+			isbns:   query.isbns,
 		}
 
-		booksToSave <- booksSave{
-			books:            results.Data,
-			word:             query.query,
-			isSearchComplete: false,
+		// isbn and id searches don't have pages
+		if query.isbns == nil && query.ids == nil {
+			save.page = page.Number
+			save.total = page.Total
+			save.isSearchComplete = isSearchComplete(wg, bookProvider, page, query, priorityQueries, queries, searchCtx)
 		}
 
+		booksToSave <- save
+
 		return
 	}
 
-	log.Fatal("Timeout! All retries failed!")
+	// the query isn't saved as searched so it will be tried again on the next run
+	log.Printf("All retries failed! Skipping query %q with %v isbns\n", query.query, len(query.isbns))
+	handleFailedSearch(wg, query, ctx, progressDb, savedData)
 }
 
-func getLinesCount(file []byte) int {
-	reader := bytes.NewReader(file)
-	scanner := bufio.NewScanner(reader)
+func printProgress(linesCount int, queriesInput *input.Input) {
+	readMegabytes := float64(queriesInput.ReadBytes()) / 1000000
+
+	fmt.Print("\033[H\033[2J") // clear console
 
-	totalLines := 0
-	for scanner.Scan() {
-		totalLines++
+	// the size of stdin is not known
+	if queriesInput.TotalBytes() == 0 {
+		fmt.Printf("Collecting... %v lines | %.1f MB\n", linesCount, readMegabytes)
+		return
 	}
 
-	return totalLines
+	totalMegabytes := float64(queriesInput.TotalBytes()) / 1000000
+	progress := int(float64(queriesInput.ReadBytes()) / float64(queriesInput.TotalBytes()) * 100)
+	fmt.Printf("Collecting... %v lines | %.1f / %.1f MB | %v%%\n", linesCount, readMegabytes, totalMegabytes, progress)
 }
 
-func getNextQuery(priorityQueries chan searchQuery, queries chan searchQuery) (searchQuery, bool) {
-	var query searchQuery
-	var ok bool
-
+// getNextQuery waits for the next query, the next pages of started queries come before new queries. It returns false
+// when the queues are closed.
+func getNextQuery(priorityQueries chan searchQuery, queries chan searchQuery, searchCtx context.Context) (searchQuery, bool) {
 	select {
-	case query, ok = <-priorityQueries:
+	case query, ok := <-priorityQueries:
+		return query, ok
 	default:
 	}
 
-	if !ok {
-		select {
-		case query, ok = <-queries:
-		default:
-		}
+	select {
+	case query, ok := <-priorityQueries:
+		return query, ok
+	case query, ok := <-queries:
+		return query, ok
+	case <-searchCtx.Done():
 	}
 
-	return query, ok
+	// searching was stopped, the queries that are still queued are received so that they are dropped
+	select {
+	case query, ok := <-priorityQueries:
+		return query, ok
+	case query, ok := <-queries:
+		return query, ok
+	}
 }
 
-func shouldTimeout(statusCode int) bool {
-	return statusCode == http.StatusGatewayTimeout || statusCode == http.StatusTooManyRequests
+// shouldRetry is true for errors that are likely to go away on their own like throttling, network issues and server
+// errors
+func shouldRetry(err error) bool {
+	var transportError *client.TransportError
+	if errors.As(err, &transportError) {
+		return true
+	}
+
+	var statusError *client.StatusError
+	if errors.As(err, &statusError) {
+		return client.IsThrottled(statusError.StatusCode) || statusError.StatusCode >= http.StatusInternalServerError
+	}
+
+	return false
 }
 
-func handleTimeout(timeoutLimiter chan struct{}, config configuration.Config) {
-	timeoutLimiter <- struct{}{}
-	time.Sleep(time.Duration(config.TimeoutSeconds) * time.Second)
-	for len(timeoutLimiter) > 0 {
-		<-timeoutLimiter
+// shouldStop is true for errors that will happen for every request, like an invalid api key
+func shouldStop(err error) bool {
+	var statusError *client.StatusError
+	if !errors.As(err, &statusError) {
+		return false
 	}
+
+	return statusError.StatusCode == http.StatusUnauthorized || statusError.StatusCode == http.StatusForbidden
 }
 
-func handleNoResults(wg *sync.WaitGroup, progressDb *sql.DB, ctx context.Context, query searchQuery) {
-	_, err := progressDb.ExecContext(ctx, `INSERT INTO searched_queries (query) VALUES (?)`, query.query)
-	if err != nil {
-		log.Fatal(err)
+// apiCallsStore saves the daily call counts to the progress database
+type apiCallsStore struct {
+	ctx        context.Context
+	progressDb *sql.DB
+	provider   string
+}
+
+func (store apiCallsStore) GetCalls(day string) int {
+	return db.GetApiCalls(store.ctx, store.progressDb, store.provider, day)
+}
+
+func (store apiCallsStore) AddCall(day string) {
+	db.AddApiCall(store.ctx, store.progressDb, store.provider, day)
+}
+
+// dropQuery is used for queries that are not searched because searching was stopped. The pages that were saved are
+// already recorded so the query continues from the next one on the next run.
+func dropQuery(wg *sync.WaitGroup) {
+	wg.Done()
+}
+
+func handleNoResults(wg *sync.WaitGroup, query searchQuery, ctx context.Context, progressDb *sql.DB, savedData db.SavedData) {
+	// refreshed books that are not found anymore are kept as they were saved
+	if query.isbns != nil {
+		savedData.SaveIsbns(ctx, progressDb, query.isbns, db.IsbnNotFound)
+	} else if query.ids == nil {
+		savedData.SaveQuery(ctx, progressDb, query.query)
+	}
+
+	wg.Done()
+}
+
+// handleFailedSearch records isbns that could not be searched so that it's possible to tell them apart from the ones
+// that were never tried, they are searched again on the next run like failed queries
+func handleFailedSearch(wg *sync.WaitGroup, query searchQuery, ctx context.Context, progressDb *sql.DB, savedData db.SavedData) {
+	if query.isbns != nil {
+		savedData.SaveIsbns(ctx, progressDb, query.isbns, db.IsbnError)
 	}
 
 	wg.Done()
 }
 
-func isSearchComplete(wg *sync.WaitGroup, resultsCount int, maxPageSize int, query searchQuery, priorityQueries chan searchQuery) bool {
-	maxPage := int(math.Ceil(float64(resultsCount) / float64(maxPageSize)))
-	isSearchComplete := query.page == maxPage
+// getFoundIsbns matches the searched isbns with the isbns of the results. If only one isbn was searched then any result
+// is a match because some providers search by other identifiers that the isbn is linked to.
+func getFoundIsbns(isbns []string, records []provider.Record) ([]string, []string) {
+	if len(isbns) == 1 && len(records) > 0 {
+		return isbns, nil
+	}
+
+	// the isbns of the records are already normalized by the providers
+	recordIsbns := make(map[string]struct{})
+	for _, record := range records {
+		recordIsbns[record.Isbn13] = struct{}{}
+	}
+
+	var found, notFound []string
+	for _, searchedIsbn := range isbns {
+		isbn13, _ := isbn.To13(searchedIsbn)
+		_, isFound := recordIsbns[isbn13]
+		if isFound && isbn13 != "" {
+			found = append(found, searchedIsbn)
+		} else {
+			notFound = append(notFound, searchedIsbn)
+		}
+	}
+
+	return found, notFound
+}
+
+func getRecheckNotFoundAfter(config configuration.Config) time.Time {
+	if config.RecheckNotFoundDays == 0 {
+		return time.Time{}
+	}
+
+	return time.Now().UTC().AddDate(0, 0, -config.RecheckNotFoundDays)
+}
+
+func isSearchComplete(
+	wg *sync.WaitGroup,
+	bookProvider provider.Provider,
+	page provider.Page,
+	query searchQuery,
+	priorityQueries chan searchQuery,
+	queries chan searchQuery,
+	searchCtx context.Context,
+) bool {
+	nextPage, hasNextPage := bookProvider.NextPage(page)
 
-	if !isSearchComplete {
-		query.page++
-		priorityQueries <- query
-		wg.Add(1)
+	if hasNextPage {
+		query.page = nextPage
+		wg.Add(1) // added before queueing because the query can be done before the send returns
+
+		// the search goroutines are the only ones that receive from the queue so they must never wait for space in it
+		select {
+		case priorityQueries <- query:
+		default:
+			go func() {
+				select {
+				case queries <- query:
+				case <-searchCtx.Done():
+					wg.Done()
+				}
+			}()
+		}
 	}
 
-	return isSearchComplete
+	return !hasNextPage
 }
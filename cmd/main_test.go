@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/zaelmyth/book-data-collector/internal/provider"
+)
+
+// pagedProvider only implements NextPage, the other methods are not used by the tests
+type pagedProvider struct {
+	provider.Provider
+}
+
+func (p pagedProvider) NextPage(page provider.Page) (int, bool) {
+	return provider.NextPageByTotal(page, 10)
+}
+
+func TestGetNextQueryPrefersNextPages(t *testing.T) {
+	queries := make(chan searchQuery, 1)
+	priorityQueries := make(chan searchQuery, 1)
+	queries <- searchQuery{query: "new", page: 1}
+	priorityQueries <- searchQuery{query: "started", page: 2}
+
+	query, ok := getNextQuery(priorityQueries, queries, context.Background())
+	if !ok || query.query != "started" {
+		t.Errorf("got %+v, %v, want the next page of the started query", query, ok)
+	}
+
+	query, ok = getNextQuery(priorityQueries, queries, context.Background())
+	if !ok || query.query != "new" {
+		t.Errorf("got %+v, %v, want the new query", query, ok)
+	}
+}
+
+func TestGetNextQueryAfterSearchingWasStopped(t *testing.T) {
+	queries := make(chan searchQuery, 1)
+	priorityQueries := make(chan searchQuery, 1)
+
+	searchCtx, stopSearching := context.WithCancel(context.Background())
+	stopSearching()
+
+	queries <- searchQuery{query: "queued", page: 1}
+	query, ok := getNextQuery(priorityQueries, queries, searchCtx)
+	if !ok || query.query != "queued" {
+		t.Errorf("got %+v, %v, want the queued query so that it's dropped", query, ok)
+	}
+
+	close(queries)
+	close(priorityQueries)
+	_, ok = getNextQuery(priorityQueries, queries, searchCtx)
+	if ok {
+		t.Error("got a query from closed queues")
+	}
+}
+
+func TestIsSearchCompleteWithAFullQueue(t *testing.T) {
+	var wg sync.WaitGroup
+	queries := make(chan searchQuery, 1)
+	priorityQueries := make(chan searchQuery) // nothing receives so it's always full
+
+	done := make(chan bool)
+	go func() {
+		done <- isSearchComplete(&wg, pagedProvider{}, provider.Page{Number: 1, Total: 25}, searchQuery{query: "tolkien", page: 1}, priorityQueries, queries, context.Background())
+	}()
+
+	select {
+	case isComplete := <-done:
+		if isComplete {
+			t.Error("the search is complete, want a next page")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("waited for space in the priority queue")
+	}
+
+	select {
+	case query := <-queries:
+		if query.query != "tolkien" || query.page != 2 {
+			t.Errorf("queued %+v, want the second page", query)
+		}
+		wg.Done()
+	case <-time.After(time.Second):
+		t.Fatal("the next page was not queued")
+	}
+
+	wg.Wait()
+}
@@ -9,12 +9,16 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"slices"
 	"strings"
 
 	_ "github.com/go-sql-driver/mysql"
 	_ "github.com/joho/godotenv/autoload"
+	_ "github.com/lib/pq"
 	"github.com/zaelmyth/book-data-collector/internal/configuration"
 	"github.com/zaelmyth/book-data-collector/internal/db"
+	"github.com/zaelmyth/book-data-collector/internal/isbn"
+	_ "modernc.org/sqlite"
 )
 
 func main() {
@@ -22,8 +26,6 @@ func main() {
 
 	config := configuration.Get()
 
-	fmt.Println("Creating open_library_id column...")
-
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
@@ -35,7 +37,7 @@ func main() {
 		}
 	}(booksDb)
 
-	db.CreateOpenLibraryIdColumn(ctx, booksDb)
+	db.CheckSchemaVersion(ctx, booksDb, db.BooksSchema)
 
 	fmt.Println("Populating open_library_id column...")
 
@@ -84,18 +86,22 @@ func main() {
 			log.Fatal(err)
 		}
 
-		if len(openLibraryEdition.Isbn13) > 0 {
-			bookId, isSaved := savedIsbn13s[openLibraryEdition.Isbn13[0]]
-			if isSaved {
-				db.UpdateOpenLibraryIdColumn(ctx, booksDb, bookId, olId)
+		// the saved isbns are normalized so every isbn of the edition is normalized before it's matched, an edition can
+		// list isbns that are not valid before the valid ones
+		for _, editionIsbn := range slices.Concat(openLibraryEdition.Isbn13, openLibraryEdition.Isbn10) {
+			isbn13, isbn10 := isbn.Normalize(editionIsbn)
+			if isbn13 == "" {
 				continue
 			}
-		}
 
-		if len(openLibraryEdition.Isbn10) > 0 {
-			bookId, isSaved := savedIsbn10s[openLibraryEdition.Isbn10[0]]
+			bookId, isSaved := savedIsbn13s[isbn13]
+			if !isSaved && isbn10 != "" {
+				bookId, isSaved = savedIsbn10s[isbn10]
+			}
+
 			if isSaved {
 				db.UpdateOpenLibraryIdColumn(ctx, booksDb, bookId, olId)
+				break
 			}
 		}
 
@@ -13,8 +13,10 @@ import (
 
 	_ "github.com/go-sql-driver/mysql"
 	_ "github.com/joho/godotenv/autoload"
+	_ "github.com/lib/pq"
 	"github.com/zaelmyth/book-data-collector/internal/configuration"
 	"github.com/zaelmyth/book-data-collector/internal/db"
+	_ "modernc.org/sqlite"
 )
 
 func main() {
@@ -22,8 +24,6 @@ func main() {
 
 	config := configuration.Get()
 
-	fmt.Println("Creating open_library_ratings table...")
-
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
@@ -35,7 +35,7 @@ func main() {
 		}
 	}(booksDb)
 
-	db.CreateOpenLibraryRatingsTable(ctx, booksDb)
+	db.CheckSchemaVersion(ctx, booksDb, db.BooksSchema)
 
 	fmt.Println("Populating open_library_ratings table...")
 
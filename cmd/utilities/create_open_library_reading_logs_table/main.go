@@ -12,8 +12,10 @@ import (
 
 	_ "github.com/go-sql-driver/mysql"
 	_ "github.com/joho/godotenv/autoload"
+	_ "github.com/lib/pq"
 	"github.com/zaelmyth/book-data-collector/internal/configuration"
 	"github.com/zaelmyth/book-data-collector/internal/db"
+	_ "modernc.org/sqlite"
 )
 
 func main() {
@@ -21,8 +23,6 @@ func main() {
 
 	config := configuration.Get()
 
-	fmt.Println("Creating open_library_reading_logs table...")
-
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
@@ -34,7 +34,7 @@ func main() {
 		}
 	}(booksDb)
 
-	db.CreateOpenLibraryReadingLogsTable(ctx, booksDb)
+	db.CheckSchemaVersion(ctx, booksDb, db.BooksSchema)
 
 	fmt.Println("Populating open_library_reading_logs table...")
 
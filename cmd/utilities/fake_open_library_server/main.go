@@ -0,0 +1,181 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/zaelmyth/book-data-collector/internal/isbn"
+)
+
+// maxResults is the max number of results a fake search can have, it's big enough to need multiple pages
+const maxResults = 250
+
+// set OPEN_LIBRARY_API_URL to the address of the server, queries and isbns ending in an odd digit are not found
+func main() {
+	log.SetFlags(log.LstdFlags | log.Lshortfile) // add code file name and line number to error messages
+
+	addressFlag := flag.String("address", "localhost:8080", "Address to listen on")
+	flag.Parse()
+
+	http.HandleFunc("GET /search.json", handleSearch)
+	http.HandleFunc("GET /isbn/{isbn}", handleIsbn)
+	http.HandleFunc("GET /books/{key}", handleEdition)
+	http.HandleFunc("GET /authors/{key}", handleAuthor)
+
+	fmt.Printf("Serving fake Open Library api on http://%v\n", *addressFlag)
+
+	log.Fatal(http.ListenAndServe(*addressFlag, nil))
+}
+
+func handleSearch(writer http.ResponseWriter, request *http.Request) {
+	query := request.URL.Query()
+	text := query.Get("title") + query.Get("subject") + query.Get("isbn") + query.Get("q")
+
+	page, err := strconv.Atoi(query.Get("page"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	limit, err := strconv.Atoi(query.Get("limit"))
+	if err != nil || limit < 1 {
+		limit = 100
+	}
+
+	total := 0
+	if !isMissing(text) {
+		total = int(hash(text)%maxResults) + 1
+	}
+
+	var docs []map[string]any
+	for i := (page - 1) * limit; i < min(page*limit, total); i++ {
+		isbn13 := fakeIsbn13(text, i)
+		isbn10, _ := isbn.To10(isbn13)
+		edition := map[string]any{
+			"key":          "/books/OL" + isbn13 + "M",
+			"title":        fmt.Sprintf("%v %v", text, i+1),
+			"isbn":         []string{isbn10, isbn13},
+			"publisher":    []string{"Fake Publisher"},
+			"language":     []string{"eng"},
+			"publish_date": []string{strconv.Itoa(1950 + i%70)},
+			"cover_i":      int(hash(isbn13) % 100000),
+		}
+
+		docs = append(docs, map[string]any{
+			"key":             "/works/OL" + isbn13 + "W",
+			"title":           fmt.Sprintf("%v %v", text, i+1),
+			"author_name":     []string{authorName(strconv.Itoa(i % 7))},
+			"subject":         []string{text, "Fiction"},
+			"ratings_average": float64(i%5) + 0.5,
+			"ratings_count":   i,
+			"editions": map[string]any{
+				"numFound": 1,
+				"docs":     []map[string]any{edition},
+			},
+		})
+	}
+
+	writeJson(writer, map[string]any{
+		"numFound": total,
+		"start":    (page - 1) * limit,
+		"docs":     docs,
+	})
+}
+
+// handleIsbn redirects to the edition the same way the real api does
+func handleIsbn(writer http.ResponseWriter, request *http.Request) {
+	value := strings.TrimSuffix(request.PathValue("isbn"), ".json")
+	if isMissing(value) {
+		http.NotFound(writer, request)
+		return
+	}
+
+	http.Redirect(writer, request, "/books/OL"+value+"M.json", http.StatusFound)
+}
+
+func handleEdition(writer http.ResponseWriter, request *http.Request) {
+	key := strings.TrimSuffix(request.PathValue("key"), ".json")
+	bookIsbn := strings.TrimSuffix(strings.TrimPrefix(key, "OL"), "M")
+
+	edition := map[string]any{
+		"key":                 "/books/" + key,
+		"title":               "Book " + bookIsbn,
+		"authors":             []map[string]string{{"key": "/authors/OL" + strconv.Itoa(int(hash(bookIsbn)%7)) + "A"}},
+		"publishers":          []string{"Fake Publisher"},
+		"publish_date":        "May 2004",
+		"number_of_pages":     int(hash(bookIsbn)%900) + 50,
+		"physical_format":     "Paperback",
+		"languages":           []map[string]string{{"key": "/languages/eng"}},
+		"subjects":            []string{"Fiction"},
+		"description":         map[string]string{"type": "/type/text", "value": "A fake book for offline testing."},
+		"covers":              []int{int(hash(bookIsbn) % 100000)},
+		"dewey_decimal_class": []string{"823.914"},
+	}
+
+	if len(bookIsbn) == 13 {
+		edition["isbn_13"] = []string{bookIsbn}
+	} else {
+		edition["isbn_10"] = []string{bookIsbn}
+	}
+
+	writeJson(writer, edition)
+}
+
+func handleAuthor(writer http.ResponseWriter, request *http.Request) {
+	key := strings.TrimSuffix(request.PathValue("key"), ".json")
+
+	writeJson(writer, map[string]any{
+		"key":  "/authors/" + key,
+		"name": authorName(strings.TrimSuffix(strings.TrimPrefix(key, "OL"), "A")),
+	})
+}
+
+func isMissing(text string) bool {
+	if text == "" {
+		return true
+	}
+
+	lastCharacter := text[len(text)-1]
+
+	return lastCharacter >= '0' && lastCharacter <= '9' && (lastCharacter-'0')%2 == 1
+}
+
+func authorName(id string) string {
+	return "Fake Author " + id
+}
+
+// fakeIsbn13 returns a valid isbn-13 that is unique per query and result
+func fakeIsbn13(text string, index int) string {
+	digits := fmt.Sprintf("978%09d", (uint64(hash(text))+uint64(index))%1000000000)
+
+	sum := 0
+	for i, digit := range digits {
+		weight := 1
+		if i%2 == 1 {
+			weight = 3
+		}
+		sum += int(digit-'0') * weight
+	}
+
+	return digits + strconv.Itoa((10-sum%10)%10)
+}
+
+func hash(text string) uint32 {
+	hash := fnv.New32a()
+	_, _ = hash.Write([]byte(text))
+
+	return hash.Sum32()
+}
+
+func writeJson(writer http.ResponseWriter, data any) {
+	writer.Header().Set("Content-Type", "application/json")
+
+	err := json.NewEncoder(writer).Encode(data)
+	if err != nil {
+		log.Println(err)
+	}
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/zaelmyth/book-data-collector/internal/isbn"
+)
+
+func TestFakeIsbn13IsValid(t *testing.T) {
+	seen := make(map[string]struct{})
+	for _, text := range []string{"tolkien", "fiction", "9780306406157", ""} {
+		for index := range 250 {
+			fakeIsbn := fakeIsbn13(text, index)
+			if !isbn.IsValid(fakeIsbn) || len(fakeIsbn) != 13 {
+				t.Fatalf("fakeIsbn13(%q, %v) = %q is not a valid isbn-13", text, index, fakeIsbn)
+			}
+
+			seen[fakeIsbn] = struct{}{}
+		}
+	}
+
+	if len(seen) < 250 {
+		t.Errorf("fakeIsbn13 returned %v unique isbns, want at least 250", len(seen))
+	}
+}
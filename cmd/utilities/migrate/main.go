@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"log"
+
+	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/joho/godotenv/autoload"
+	_ "github.com/lib/pq"
+	"github.com/zaelmyth/book-data-collector/internal/configuration"
+	"github.com/zaelmyth/book-data-collector/internal/db"
+	_ "modernc.org/sqlite"
+)
+
+// usage: migrate [status|up|down]
+func main() {
+	log.SetFlags(log.LstdFlags | log.Lshortfile) // add code file name and line number to error messages
+
+	config := configuration.GetDatabase()
+
+	command := flag.Arg(0)
+	if command == "" {
+		command = "status"
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if command == "up" {
+		db.CreateDatabases(ctx, config)
+	}
+
+	booksDb := db.GetBooksDatabase(config)
+	defer func(db *sql.DB) {
+		err := db.Close()
+		if err != nil {
+			log.Fatal(err)
+		}
+	}(booksDb)
+
+	progressDb := db.GetProgressDatabase(config)
+	defer func(db *sql.DB) {
+		err := db.Close()
+		if err != nil {
+			log.Fatal(err)
+		}
+	}(progressDb)
+
+	switch command {
+	case "status":
+		printStatus(ctx, booksDb, progressDb)
+	case "up":
+		appliedCount := db.MigrateUp(ctx, booksDb, progressDb)
+		fmt.Printf("Applied %v migrations\n", appliedCount)
+	case "down":
+		if !db.MigrateDown(ctx, booksDb, progressDb) {
+			fmt.Println("There are no migrations to revert")
+		}
+	default:
+		log.Fatal("Invalid command, use status, up or down")
+	}
+}
+
+func printStatus(ctx context.Context, booksDb *sql.DB, progressDb *sql.DB) {
+	for _, status := range db.GetMigrationStatus(ctx, booksDb, progressDb) {
+		appliedAt := "pending"
+		if status.IsApplied {
+			appliedAt = "applied " + status.AppliedAt.Format("2006-01-02 15:04:05")
+		}
+
+		fmt.Printf("%v %v (%v): %v\n", status.Version, status.Name, status.Schema, appliedAt)
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+
+	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/joho/godotenv/autoload"
+	_ "github.com/lib/pq"
+	"github.com/zaelmyth/book-data-collector/internal/configuration"
+	"github.com/zaelmyth/book-data-collector/internal/db"
+	_ "modernc.org/sqlite"
+)
+
+// the collector should not be running while authors are merged
+func main() {
+	log.SetFlags(log.LstdFlags | log.Lshortfile) // add code file name and line number to error messages
+
+	config := configuration.GetDatabase()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	booksDb := db.GetBooksDatabase(config)
+	defer func(db *sql.DB) {
+		err := db.Close()
+		if err != nil {
+			log.Fatal(err)
+		}
+	}(booksDb)
+
+	db.CheckSchemaVersion(ctx, booksDb, db.BooksSchema)
+
+	fmt.Println("Normalizing authors...")
+
+	mergedAuthors, movedLinks := db.NormalizeAuthors(ctx, booksDb)
+	fmt.Printf("Merged %v authors and moved %v book links to the normalized authors\n", mergedAuthors, movedLinks)
+
+	fmt.Println("Done!")
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"sync"
+
+	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/joho/godotenv/autoload"
+	_ "github.com/lib/pq"
+	_ "github.com/zaelmyth/book-data-collector/google"
+	"github.com/zaelmyth/book-data-collector/internal/client"
+	"github.com/zaelmyth/book-data-collector/internal/configuration"
+	"github.com/zaelmyth/book-data-collector/internal/db"
+	"github.com/zaelmyth/book-data-collector/internal/provider"
+	_ "github.com/zaelmyth/book-data-collector/isbndb"
+	_ "github.com/zaelmyth/book-data-collector/openlibrary"
+	_ "modernc.org/sqlite"
+)
+
+// responsesPerBatch is how many archived responses are read from the database at once
+const responsesPerBatch = 100
+
+// prices are saved again as snapshots so the archive should be ingested into a new books database
+func main() {
+	log.SetFlags(log.LstdFlags | log.Lshortfile) // add code file name and line number to error messages
+
+	config := configuration.GetDatabase()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	booksDb := db.GetBooksDatabase(config)
+	defer func(db *sql.DB) {
+		err := db.Close()
+		if err != nil {
+			log.Fatal(err)
+		}
+	}(booksDb)
+
+	progressDb := db.GetProgressDatabase(config)
+	defer func(db *sql.DB) {
+		err := db.Close()
+		if err != nil {
+			log.Fatal(err)
+		}
+	}(progressDb)
+
+	db.CheckSchemaVersion(ctx, progressDb, db.ProgressSchema)
+	db.CheckSchemaVersion(ctx, booksDb, db.BooksSchema)
+
+	bookProvider := provider.Get(config)
+	archive := db.NewResponseArchive(ctx, progressDb, config.Provider)
+
+	// the calls that providers make while mapping responses, like the open library author lookups, are answered by the
+	// archive
+	client.SetReplay(archive)
+
+	savedData := db.SavedData{
+		Books:              db.GetSavedData(ctx, booksDb, "books", bookProvider.IdColumn()),
+		SavingBooks:        make(map[string]struct{}),
+		BooksMutex:         &sync.RWMutex{},
+		Authors:            db.GetSavedDataWithId(ctx, booksDb, "authors", "name_key"),
+		AuthorsMutex:       &sync.Mutex{},
+		AuthorAliases:      db.GetSavedData(ctx, booksDb, "author_aliases", "alias"),
+		AuthorAliasesMutex: &sync.Mutex{},
+		Subjects:           db.GetSavedDataWithId(ctx, booksDb, "subjects", "name"),
+		SubjectsMutex:      &sync.Mutex{},
+		Publishers:         db.GetSavedDataWithId(ctx, booksDb, "publishers", "name"),
+		PublishersMutex:    &sync.Mutex{},
+		Languages:          db.GetSavedDataWithId(ctx, booksDb, "languages", "name"),
+		LanguagesMutex:     &sync.Mutex{},
+	}
+
+	totalResponses := archive.CountApiResponses()
+	fmt.Printf("Ingesting %v archived responses...\n", totalResponses)
+
+	lastId := 0
+	responsesCount := 0
+	failedCount := 0
+	for {
+		responses := archive.GetApiResponses(lastId, responsesPerBatch)
+		if len(responses) == 0 {
+			break
+		}
+
+		for _, response := range responses {
+			records, err := bookProvider.Decode(ctx, response.Endpoint, response.Body)
+			if err != nil {
+				// the response is skipped so that the rest of the archive is still ingested
+				log.Printf("Response %v of %v could not be decoded: %v\n", response.Id, response.Endpoint, err)
+				failedCount++
+			} else {
+				// prices are snapshots of when the response was received
+				db.SaveBooksAt(ctx, booksDb, bookProvider.IdColumn(), records, savedData, true, response.CreatedAt.UTC())
+			}
+
+			lastId = response.Id
+			responsesCount++
+		}
+
+		fmt.Printf("%v / %v responses ingested...\n", responsesCount, totalResponses)
+	}
+
+	if failedCount > 0 {
+		fmt.Printf("%v responses could not be decoded\n", failedCount)
+	}
+
+	fmt.Println("Done!")
+}
@@ -1,6 +1,7 @@
 package google
 
 import (
+	"context"
 	"log"
 	"net/url"
 	"slices"
@@ -14,7 +15,7 @@ const MaxPageSize = 40
 
 // Search There are special keywords you can specify in the search terms to search in particular fields:
 // https://developers.google.com/books/docs/v1/using#PerformingSearch
-func Search(query string, parameters SearchParameters) (SearchResults, int) {
+func Search(ctx context.Context, query string, parameters SearchParameters) (SearchResults, int, error) {
 	if query == "" {
 		log.Fatal("Empty query")
 	}
@@ -55,15 +56,15 @@ func Search(query string, parameters SearchParameters) (SearchResults, int) {
 		requestData.Add("langRestrict", parameters.Language)
 	}
 
-	return call("get", "/volumes", requestData, SearchResults{})
+	return call(ctx, "get", "/volumes", requestData, SearchResults{})
 }
 
-func VolumeDetails(id string) (Volume, int) {
-	return call("get", "/volumes/"+id, url.Values{}, Volume{})
+func VolumeDetails(ctx context.Context, id string) (Volume, int, error) {
+	return call(ctx, "get", "/volumes/"+url.PathEscape(id), url.Values{}, Volume{})
 }
 
-func call[T any](method string, url string, data url.Values, responseStruct T) (T, int) {
-	return client.Call(method, apiUrl+url, data, map[string]string{}, responseStruct)
+func call[T any](ctx context.Context, method string, url string, data url.Values, responseStruct T) (T, int, error) {
+	return client.Call(ctx, method, apiUrl+url, data, map[string]string{}, responseStruct)
 }
 
 func validateSearchParameters(parameters SearchParameters) {
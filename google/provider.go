@@ -0,0 +1,240 @@
+package google
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/zaelmyth/book-data-collector/internal/configuration"
+	"github.com/zaelmyth/book-data-collector/internal/isbn"
+	"github.com/zaelmyth/book-data-collector/internal/provider"
+)
+
+func init() {
+	provider.Register("google", NewProvider)
+}
+
+type Provider struct {
+	keyword string
+}
+
+func NewProvider(config configuration.Config) provider.Provider {
+	keyword := config.SearchBy
+	if keyword == "title" {
+		keyword = "intitle"
+	}
+
+	return Provider{keyword: keyword}
+}
+
+func (p Provider) IdColumn() string {
+	return "google_id"
+}
+
+// IsbnBatchSize is 1 because isbns are looked up with a search query and the api doesn't support searching for
+// multiple isbns at once
+func (p Provider) IsbnBatchSize() int {
+	return 1
+}
+
+// MaxCallsPerSecond the quota depends on the google cloud project so this is a safe default for the free quota
+func (p Provider) MaxCallsPerSecond() int {
+	return 1
+}
+
+// MaxCallsPerDay is the default quota of a google cloud project
+func (p Provider) MaxCallsPerDay() int {
+	return 1000
+}
+
+func (p Provider) Search(ctx context.Context, query string, page int) (provider.Page, error) {
+	return search(ctx, p.keyword+":"+query, page)
+}
+
+func (p Provider) SearchByIsbn(ctx context.Context, isbns []string) (provider.Page, error) {
+	if len(isbns) != 1 {
+		log.Fatal("Only one ISBN can be searched at a time")
+	}
+
+	return search(ctx, "isbn:"+isbns[0], 1)
+}
+
+// SearchById looks up the volume itself because an isbn search can return other volumes of the same book
+func (p Provider) SearchById(ctx context.Context, ids []string) (provider.Page, error) {
+	if len(ids) != 1 {
+		log.Fatal("Only one id can be searched at a time")
+	}
+
+	volume, _, err := VolumeDetails(ctx, ids[0])
+	if err != nil || volume.Id == "" {
+		return provider.Page{Number: 1}, err
+	}
+
+	return provider.Page{
+		Number:  1,
+		Total:   1,
+		Records: []provider.Record{toRecord(volume)},
+	}, nil
+}
+
+func (p Provider) NextPage(page provider.Page) (int, bool) {
+	return provider.NextPageByTotal(page, MaxPageSize)
+}
+
+// Decode maps the volumes and volume endpoints, the path of the endpoint includes the path of the api url
+func (p Provider) Decode(ctx context.Context, endpoint string, body []byte) ([]provider.Record, error) {
+	switch {
+	case strings.HasSuffix(endpoint, "/volumes"):
+		var results SearchResults
+		err := json.Unmarshal(body, &results)
+		return toRecords(results.Items), err
+	case strings.Contains(endpoint, "/volumes/"):
+		var volume Volume
+		err := json.Unmarshal(body, &volume)
+		if err != nil || volume.Id == "" {
+			return nil, err
+		}
+		return []provider.Record{toRecord(volume)}, nil
+	default:
+		return nil, nil
+	}
+}
+
+func search(ctx context.Context, query string, page int) (provider.Page, error) {
+	results, _, err := Search(ctx, query, SearchParameters{
+		Filter:     "full",
+		StartIndex: (page - 1) * MaxPageSize,
+		MaxResults: MaxPageSize,
+		PrintType:  "books",
+		Projection: "full",
+	})
+
+	return provider.Page{
+		Number:  page,
+		Total:   results.TotalItems,
+		Records: toRecords(results.Items),
+	}, err
+}
+
+func toRecords(volumes []Volume) []provider.Record {
+	records := make([]provider.Record, 0, len(volumes))
+	for _, volume := range volumes {
+		records = append(records, toRecord(volume))
+	}
+
+	return records
+}
+
+func toRecord(volume Volume) provider.Record {
+	record := provider.Record{
+		Id:            volume.Id,
+		GoogleId:      volume.Id,
+		Title:         volume.VolumeInfo.Title,
+		Subtitle:      volume.VolumeInfo.Subtitle,
+		Publisher:     volume.VolumeInfo.Publisher,
+		Language:      volume.VolumeInfo.Language,
+		DatePublished: volume.VolumeInfo.PublishedDate,
+		Synopsis:      volume.VolumeInfo.Description,
+		Pages:         volume.VolumeInfo.PageCount,
+		AverageRating: volume.VolumeInfo.AverageRating,
+		RatingCount:   volume.VolumeInfo.RatingsCount,
+		MainCategory:  volume.VolumeInfo.MainCategory,
+		Authors:       volume.VolumeInfo.Authors,
+		Subjects:      volume.VolumeInfo.Categories,
+	}
+
+	var isbn13, isbn10 string
+	for _, industryIdentifier := range volume.VolumeInfo.IndustryIdentifiers {
+		identifier := industryIdentifier.Identifier
+
+		// isbns are saved without hyphens so that they match the isbn columns, invalid ones are dropped
+		isValid := true
+		switch industryIdentifier.Type {
+		case "ISBN_10":
+			identifier, isValid = isbn.To10(identifier)
+			if isValid {
+				isbn10 = identifier
+			}
+		case "ISBN_13":
+			identifier, isValid = isbn.To13(identifier)
+			if isValid {
+				isbn13 = identifier
+			}
+		}
+
+		if !isValid {
+			continue
+		}
+
+		record.IndustryIdentifiers = append(record.IndustryIdentifiers, provider.IndustryIdentifier{
+			Type:       industryIdentifier.Type,
+			Identifier: identifier,
+		})
+	}
+
+	record.Isbn13, record.Isbn = isbn.Normalize(isbn13, isbn10)
+
+	// the list and retail prices are the same as the ones of the offers, offers are only returned for some countries
+	saleInfo := volume.SaleInfo
+	if saleInfo.Saleability == "FOR_SALE" && saleInfo.RetailPrice.CurrencyCode != "" {
+		price := provider.Price{
+			Merchant: "Google Play",
+			Country:  saleInfo.Country,
+			Currency: saleInfo.RetailPrice.CurrencyCode,
+			Price:    &saleInfo.RetailPrice.Amount,
+			Total:    &saleInfo.RetailPrice.Amount,
+			Link:     saleInfo.BuyLink,
+		}
+
+		if saleInfo.ListPrice.CurrencyCode != "" {
+			price.ListPrice = &saleInfo.ListPrice.Amount
+		}
+
+		record.Prices = append(record.Prices, price)
+	}
+
+	imageLinks := volume.VolumeInfo.ImageLinks
+	sizes := map[string]string{
+		"extraLarge":     imageLinks.ExtraLarge,
+		"large":          imageLinks.Large,
+		"medium":         imageLinks.Medium,
+		"small":          imageLinks.Small,
+		"thumbnail":      imageLinks.Thumbnail,
+		"smallThumbnail": imageLinks.SmallThumbnail,
+	}
+	for size, url := range sizes {
+		if url != "" {
+			record.ImageLinks = append(record.ImageLinks, provider.ImageLink{Size: size, Url: url})
+		}
+	}
+
+	for _, series := range volume.VolumeInfo.SeriesInfo.VolumeSeries {
+		record.Series = append(record.Series, provider.Series{
+			SeriesId:    series.SeriesId,
+			BookType:    series.SeriesBookType,
+			OrderNumber: series.OrderNumber,
+		})
+	}
+
+	accessInfo := volume.AccessInfo
+	record.Access = provider.Access{
+		Country:                accessInfo.Country,
+		Viewability:            accessInfo.Viewability,
+		AccessViewStatus:       accessInfo.AccessViewStatus,
+		TextToSpeechPermission: accessInfo.TextToSpeechPermission,
+		MaturityRating:         volume.VolumeInfo.MaturityRating,
+		Embeddable:             accessInfo.Embeddable,
+		PublicDomain:           accessInfo.PublicDomain,
+		EpubAvailable:          accessInfo.Epub.IsAvailable,
+		PdfAvailable:           accessInfo.Pdf.IsAvailable,
+	}
+
+	dimensions := volume.VolumeInfo.Dimensions
+	if dimensions.Height > 0 || dimensions.Width > 0 || dimensions.Thickness > 0 {
+		record.Dimensions = fmt.Sprintf("%v x %v x %v", dimensions.Height, dimensions.Width, dimensions.Thickness)
+	}
+
+	return record
+}
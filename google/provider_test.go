@@ -0,0 +1,95 @@
+package google
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"testing"
+
+	"github.com/zaelmyth/book-data-collector/internal/provider"
+)
+
+func TestToRecordNormalizesIsbns(t *testing.T) {
+	tests := []struct {
+		name                string
+		identifiers         string
+		isbn13              string
+		isbn10              string
+		industryIdentifiers []provider.IndustryIdentifier
+	}{
+		{
+			name:        "hyphenated isbns",
+			identifiers: `[{"type": "ISBN_10", "identifier": "0-261-10334-2"}, {"type": "ISBN_13", "identifier": "978-0-261-10334-4"}]`,
+			isbn13:      "9780261103344",
+			isbn10:      "0261103342",
+			industryIdentifiers: []provider.IndustryIdentifier{
+				{Type: "ISBN_10", Identifier: "0261103342"},
+				{Type: "ISBN_13", Identifier: "9780261103344"},
+			},
+		},
+		{
+			name:        "invalid isbns are dropped",
+			identifiers: `[{"type": "ISBN_13", "identifier": "9780261103345"}, {"type": "ISBN_10", "identifier": "080442957x"}]`,
+			isbn13:      "9780804429573",
+			isbn10:      "080442957X",
+			industryIdentifiers: []provider.IndustryIdentifier{
+				{Type: "ISBN_10", Identifier: "080442957X"},
+			},
+		},
+		{
+			name:        "other identifiers are kept as they are",
+			identifiers: `[{"type": "OTHER", "identifier": "UOM:39015008294123"}]`,
+			industryIdentifiers: []provider.IndustryIdentifier{
+				{Type: "OTHER", Identifier: "UOM:39015008294123"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var volume Volume
+			err := json.Unmarshal([]byte(`{"id": "pD6arNyKyi8C", "volumeInfo": {"industryIdentifiers": `+test.identifiers+`}}`), &volume)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			record := toRecord(volume)
+			if record.Isbn13 != test.isbn13 || record.Isbn != test.isbn10 {
+				t.Errorf("isbns = %q, %q, want %q, %q", record.Isbn13, record.Isbn, test.isbn13, test.isbn10)
+			}
+			if !slices.Equal(record.IndustryIdentifiers, test.industryIdentifiers) {
+				t.Errorf("industry identifiers = %v, want %v", record.IndustryIdentifiers, test.industryIdentifiers)
+			}
+		})
+	}
+}
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		endpoint string
+		body     string
+		wantIds  []string
+	}{
+		{endpoint: "/books/v1/volumes", body: `{"totalItems": 2, "items": [{"id": "pD6arNyKyi8C"}, {"id": "hFfhrCWiLSMC"}]}`, wantIds: []string{"pD6arNyKyi8C", "hFfhrCWiLSMC"}},
+		{endpoint: "/books/v1/volumes/pD6arNyKyi8C", body: `{"id": "pD6arNyKyi8C"}`, wantIds: []string{"pD6arNyKyi8C"}},
+		{endpoint: "/books/v1/volumes/pD6arNyKyi8C", body: `{}`},
+		{endpoint: "/books/v1/mylibrary/bookshelves", body: `{}`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.endpoint, func(t *testing.T) {
+			records, err := Provider{}.Decode(context.Background(), test.endpoint, []byte(test.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var ids []string
+			for _, record := range records {
+				ids = append(ids, record.GoogleId)
+			}
+			if !slices.Equal(ids, test.wantIds) {
+				t.Errorf("ids = %q, want %q", ids, test.wantIds)
+			}
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package authorname
+
+import (
+	"slices"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+)
+
+// suffixes are the parts of a name that come after a comma without the name being inverted
+var suffixes = []string{"jr", "sr", "ii", "iii", "iv", "phd", "md", "esq", "inc", "ltd", "llc", "co"}
+
+// organizationWords are the words of the names of publishers and organizations, which are never inverted
+var organizationWords = []string{
+	"books", "press", "house", "publishing", "publishers", "publications", "company", "group", "media", "society",
+	"university", "institute", "association", "library", "museum", "foundation", "corporation", "council", "committee",
+	"department", "office", "inc", "ltd", "llc", "co",
+}
+
+// letters are the letters that are not decomposed into a letter and a diacritic
+var letters = strings.NewReplacer("ø", "o", "Ø", "o", "ł", "l", "Ł", "l", "đ", "d", "Đ", "d", "æ", "ae", "Æ", "ae", "œ", "oe", "Œ", "oe", "þ", "th", "Þ", "th")
+
+var folder = cases.Fold()
+
+// Normalize returns the name the way it's saved, with the whitespace collapsed, inverted names like "Tolkien, J.R.R."
+// put in order and initials spaced like "J. R. R. Tolkien"
+func Normalize(name string) string {
+	name = strings.Join(strings.Fields(name), " ")
+
+	parts := strings.Split(name, ", ")
+	switch {
+	case len(parts) == 2 && isInverted(parts[0], parts[1]):
+		name = parts[1] + " " + parts[0]
+	case len(parts) == 3 && isInverted(parts[0], parts[1]) && isSuffix(parts[2]):
+		name = parts[1] + " " + parts[0] + ", " + parts[2]
+	}
+
+	words := strings.Fields(name)
+	for i, word := range words {
+		if isInitials(word) {
+			words[i] = strings.Join(strings.Split(strings.TrimSuffix(word, "."), "."), ". ") + "."
+		}
+	}
+
+	return strings.Join(words, " ")
+}
+
+// Key returns the normalized name without case, diacritics and punctuation. Names that have the same key are written
+// differently but are the same author.
+func Key(name string) string {
+	name = norm.NFD.String(Normalize(name))
+
+	name = strings.Map(func(char rune) rune {
+		switch {
+		case unicode.Is(unicode.Mn, char):
+			return -1
+		case unicode.IsLetter(char) || unicode.IsDigit(char):
+			return char
+		default:
+			return ' '
+		}
+	}, letters.Replace(name))
+
+	return strings.Join(strings.Fields(folder.String(name)), " ")
+}
+
+// isInverted is true if the name is the family name followed by the given names, it's not if the part after the comma
+// is a suffix or doesn't look like given names, like the ones of publishers and organizations
+func isInverted(familyName string, givenNames string) bool {
+	if isSuffix(givenNames) || len(strings.Fields(familyName)) > 3 || len(strings.Fields(givenNames)) > 4 {
+		return false
+	}
+
+	if isOrganization(familyName) || isOrganization(givenNames) {
+		return false
+	}
+
+	for _, word := range strings.Fields(givenNames) {
+		if !unicode.IsUpper([]rune(word)[0]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func isOrganization(value string) bool {
+	if strings.Contains(value, "&") {
+		return true
+	}
+
+	for _, word := range strings.Fields(strings.ToLower(strings.ReplaceAll(value, ".", ""))) {
+		if slices.Contains(organizationWords, word) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isSuffix(value string) bool {
+	value = strings.ToLower(strings.ReplaceAll(value, ".", ""))
+	for _, suffix := range suffixes {
+		if value == suffix {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isInitials is true for a word of more than one initial like "J.R.R." or "J.R.R"
+func isInitials(word string) bool {
+	initials := strings.Split(strings.TrimSuffix(word, "."), ".")
+	if len(initials) < 2 {
+		return false
+	}
+
+	for _, initial := range initials {
+		runes := []rune(initial)
+		if len(runes) != 1 || !unicode.IsUpper(runes[0]) {
+			return false
+		}
+	}
+
+	return true
+}
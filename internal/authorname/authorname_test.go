@@ -0,0 +1,68 @@
+package authorname
+
+import (
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{name: "J. R. R. Tolkien", want: "J. R. R. Tolkien"},
+		{name: "J.R.R. Tolkien", want: "J. R. R. Tolkien"},
+		{name: "J.R.R Tolkien", want: "J. R. R. Tolkien"},
+		{name: "Tolkien, J.R.R.", want: "J. R. R. Tolkien"},
+		{name: "Milne, A. A.", want: "A. A. Milne"},
+		{name: "Brontë, Charlotte", want: "Charlotte Brontë"},
+		{name: "  J. R. R.   Tolkien\t", want: "J. R. R. Tolkien"},
+		{name: "King, Martin Luther, Jr.", want: "Martin Luther King, Jr."},
+		{name: "Martin Luther King, Jr.", want: "Martin Luther King, Jr."},
+		{name: "Penguin Books, Inc.", want: "Penguin Books, Inc."},
+		{name: "Oxford University Press, the", want: "Oxford University Press, the"},
+		{name: "Penguin, Random House", want: "Penguin, Random House"},
+		{name: "Smith, Elder & Co.", want: "Smith, Elder & Co."},
+		{name: "Harper & Row, Publishers", want: "Harper & Row, Publishers"},
+		{name: "Folio Society, The", want: "Folio Society, The"},
+		{name: "Homer", want: "Homer"},
+		{name: "", want: ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := Normalize(test.name)
+			if got != test.want {
+				t.Errorf("Normalize(%q) = %q, want %q", test.name, got, test.want)
+			}
+		})
+	}
+}
+
+func TestKey(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{name: "J. R. R. Tolkien", want: "j r r tolkien"},
+		{name: "Tolkien, J.R.R.", want: "j r r tolkien"},
+		{name: "j.r.r. tolkien", want: "j r r tolkien"},
+		{name: "Charlotte Brontë", want: "charlotte bronte"},
+		{name: "Charlotte Bronte", want: "charlotte bronte"},
+		{name: "GABRIEL GARCÍA MÁRQUEZ", want: "gabriel garcia marquez"},
+		{name: "Søren Kierkegaard", want: "soren kierkegaard"},
+		{name: "Stanisław Lem", want: "stanislaw lem"},
+		{name: "Johann Strauß", want: "johann strauss"},
+		{name: "King, Martin Luther, Jr.", want: "martin luther king jr"},
+		{name: "  Homer ", want: "homer"},
+		{name: "", want: ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := Key(test.name)
+			if got != test.want {
+				t.Errorf("Key(%q) = %q, want %q", test.name, got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package batcher
+
+import (
+	"sync"
+	"time"
+)
+
+// Batcher groups values into batches of a fixed size. Partial batches are flushed when no value was added for the idle
+// timeout, so that streamed input doesn't wait forever for a batch to fill up, and when the batcher is closed.
+//
+// Values are de-duplicated against the last remembered values, which covers the current batch and the batches before
+// it while keeping memory bounded.
+type Batcher struct {
+	mutex       sync.Mutex
+	size        int
+	idleTimeout time.Duration
+	flush       func(batch []string)
+	batch       []string
+	recent      *recentSet
+	timer       *time.Timer
+	isClosed    bool
+}
+
+func New(size int, idleTimeout time.Duration, remembered int, flush func(batch []string)) *Batcher {
+	return &Batcher{
+		size:        size,
+		idleTimeout: idleTimeout,
+		flush:       flush,
+		batch:       make([]string, 0, size),
+		recent:      newRecentSet(max(remembered, size)),
+	}
+}
+
+// Add returns false if the value was a duplicate
+func (b *Batcher) Add(value string) bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.isClosed || !b.recent.add(value) {
+		return false
+	}
+
+	b.batch = append(b.batch, value)
+	if len(b.batch) == b.size {
+		b.flushBatch()
+		return true
+	}
+
+	if b.timer == nil {
+		b.timer = time.AfterFunc(b.idleTimeout, b.flushIdle)
+	} else {
+		b.timer.Reset(b.idleTimeout)
+	}
+
+	return true
+}
+
+// Close flushes the last partial batch, values added after closing are ignored
+func (b *Batcher) Close() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.timer != nil {
+		b.timer.Stop()
+	}
+
+	b.flushBatch()
+	b.isClosed = true
+}
+
+func (b *Batcher) flushIdle() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.flushBatch()
+}
+
+func (b *Batcher) flushBatch() {
+	if len(b.batch) == 0 {
+		return
+	}
+
+	batch := b.batch
+	b.batch = make([]string, 0, b.size)
+
+	b.flush(batch)
+}
+
+// recentSet remembers the last values that were added, the oldest value is forgotten when a new one doesn't fit
+type recentSet struct {
+	values map[string]struct{}
+	order  []string
+	next   int
+}
+
+func newRecentSet(capacity int) *recentSet {
+	return &recentSet{
+		values: make(map[string]struct{}, capacity),
+		order:  make([]string, 0, capacity),
+	}
+}
+
+// add returns false if the value is already in the set
+func (set *recentSet) add(value string) bool {
+	_, exists := set.values[value]
+	if exists {
+		return false
+	}
+
+	if len(set.order) < cap(set.order) {
+		set.order = append(set.order, value)
+	} else {
+		delete(set.values, set.order[set.next])
+		set.order[set.next] = value
+		set.next = (set.next + 1) % len(set.order)
+	}
+
+	set.values[value] = struct{}{}
+
+	return true
+}
@@ -0,0 +1,141 @@
+package batcher
+
+import (
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recorder keeps the flushed batches, flushes on idle happen on the timer's goroutine
+type recorder struct {
+	mutex   sync.Mutex
+	batches [][]string
+	flushed chan struct{}
+}
+
+func newRecorder() *recorder {
+	return &recorder{flushed: make(chan struct{}, 100)}
+}
+
+func (r *recorder) flush(batch []string) {
+	r.mutex.Lock()
+	r.batches = append(r.batches, batch)
+	r.mutex.Unlock()
+
+	r.flushed <- struct{}{}
+}
+
+func (r *recorder) Batches() [][]string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return slices.Clone(r.batches)
+}
+
+func TestBatcherFlushesOnSize(t *testing.T) {
+	tests := []struct {
+		name        string
+		size        int
+		values      []string
+		wantBatches [][]string
+	}{
+		{"no full batch", 3, []string{"a", "b"}, nil},
+		{"one full batch", 2, []string{"a", "b", "c"}, [][]string{{"a", "b"}}},
+		{"several full batches", 2, []string{"a", "b", "c", "d"}, [][]string{{"a", "b"}, {"c", "d"}}},
+		{"duplicates don't fill the batch", 2, []string{"a", "a", "b"}, [][]string{{"a", "b"}}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			recorder := newRecorder()
+			batcher := New(test.size, time.Hour, 10, recorder.flush)
+
+			for _, value := range test.values {
+				batcher.Add(value)
+			}
+
+			if !slices.EqualFunc(recorder.Batches(), test.wantBatches, slices.Equal) {
+				t.Errorf("batches = %v, want %v", recorder.Batches(), test.wantBatches)
+			}
+		})
+	}
+}
+
+func TestBatcherFlushesOnIdle(t *testing.T) {
+	recorder := newRecorder()
+	batcher := New(10, 10*time.Millisecond, 10, recorder.flush)
+
+	batcher.Add("a")
+	batcher.Add("b")
+
+	select {
+	case <-recorder.flushed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the partial batch was not flushed")
+	}
+
+	want := [][]string{{"a", "b"}}
+	if !slices.EqualFunc(recorder.Batches(), want, slices.Equal) {
+		t.Errorf("batches = %v, want %v", recorder.Batches(), want)
+	}
+}
+
+func TestBatcherClose(t *testing.T) {
+	recorder := newRecorder()
+	batcher := New(10, time.Hour, 10, recorder.flush)
+
+	batcher.Add("a")
+	batcher.Close()
+
+	if batcher.Add("b") {
+		t.Error("a value was added after closing")
+	}
+
+	want := [][]string{{"a"}}
+	if !slices.EqualFunc(recorder.Batches(), want, slices.Equal) {
+		t.Errorf("batches = %v, want %v", recorder.Batches(), want)
+	}
+}
+
+func TestBatcherAddReturnsFalseForDuplicates(t *testing.T) {
+	batcher := New(2, time.Hour, 10, func(batch []string) {})
+
+	results := []bool{batcher.Add("a"), batcher.Add("b"), batcher.Add("a"), batcher.Add("c"), batcher.Add("b")}
+
+	// values are remembered after their batch was flushed
+	want := []bool{true, true, false, true, false}
+	if !slices.Equal(results, want) {
+		t.Errorf("results = %v, want %v", results, want)
+	}
+}
+
+func TestRecentSet(t *testing.T) {
+	tests := []struct {
+		name     string
+		capacity int
+		values   []string
+		want     []bool
+	}{
+		{"new values", 3, []string{"a", "b", "c"}, []bool{true, true, true}},
+		{"duplicates", 3, []string{"a", "b", "a", "b"}, []bool{true, true, false, false}},
+		{"oldest value is forgotten", 2, []string{"a", "b", "c", "a"}, []bool{true, true, true, true}},
+		{"newer values are remembered", 2, []string{"a", "b", "c", "b", "c"}, []bool{true, true, true, false, false}},
+		{"forgetting wraps around", 2, []string{"a", "b", "c", "d", "e", "d"}, []bool{true, true, true, true, true, false}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			set := newRecentSet(test.capacity)
+
+			var results []bool
+			for _, value := range test.values {
+				results = append(results, set.add(value))
+			}
+
+			if !slices.Equal(results, test.want) {
+				t.Errorf("results = %v, want %v", results, test.want)
+			}
+		})
+	}
+}
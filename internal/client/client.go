@@ -2,67 +2,200 @@ package client
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"io"
 	"log"
 	"net/http"
 	"net/url"
-	"slices"
+	"strconv"
 	"strings"
 	"time"
 )
 
 const apiTimeoutSeconds = 120
 
-func Call[T any](method string, url string, data url.Values, headers map[string]string, responseStruct T) (T, int) {
+// limiter is shared by all calls so that every api request counts towards the same rate
+var limiter *Limiter
+
+// quota is shared by all calls so that every api request counts towards the daily limit
+var quota *Quota
+
+// archive is shared by all calls so that every successful response is kept
+var archive Archive
+
+// replay is shared by all calls so that no call reaches the api while the archive is ingested again
+var replay Archive
+
+// Archive keeps the raw bodies of successful responses, requests are identified by their url and data
+type Archive interface {
+	Save(url string, data url.Values, body []byte)
+	// Find returns the body of the last archived response to the request and false if it was never archived
+	Find(url string, data url.Values) ([]byte, bool)
+}
+
+// SetLimiter makes every following call wait for the limiter, without it calls are made as soon as possible
+func SetLimiter(l *Limiter) {
+	limiter = l
+}
+
+// SetQuota makes every following call count towards the daily quota, without it there is no daily limit
+func SetQuota(q *Quota) {
+	quota = q
+}
+
+// SetArchive makes every following call save the body of its response to the archive, without it responses are only
+// decoded
+func SetArchive(a Archive) {
+	archive = a
+}
+
+// SetReplay makes every following call return the archived response instead of calling the api, requests that were
+// never archived are treated as not found
+func SetReplay(a Archive) {
+	replay = a
+}
+
+// Call returns the status code and a nil error for successful and not found responses, not found is not treated as an
+// error because the apis use it for searches without results. Every other response returns one of the errors in
+// errors.go so the caller can decide if it should retry, skip or stop. Calls over the daily quota are not made and
+// return a QuotaExceededError. The context only cancels waiting for the limiter and the quota, a request that was
+// already sent is always finished because the api counts it either way.
+func Call[T any](ctx context.Context, method string, url string, data url.Values, headers map[string]string, responseStruct T) (T, int, error) {
+	if replay != nil {
+		return replayCall(url, data, responseStruct)
+	}
+
 	httpClient := http.Client{
 		Timeout: apiTimeoutSeconds * time.Second,
 	}
 
 	var request *http.Request
+	var err error
 	if method == "post" {
-		request = createPostRequest(data, url, headers)
+		request, err = createPostRequest(context.WithoutCancel(ctx), data, url, headers)
 	} else {
-		request = createGetRequest(url, data, headers)
+		request, err = createGetRequest(context.WithoutCancel(ctx), url, data, headers)
+	}
+	if err != nil {
+		return responseStruct, 0, err
+	}
+
+	if limiter != nil {
+		err = limiter.Wait(ctx)
+		if err != nil {
+			return responseStruct, 0, err
+		}
+	}
+
+	// the quota is taken after waiting for the limiter so that calls cancelled while waiting don't use it up
+	if quota != nil {
+		err = quota.Take(ctx)
+		if err != nil {
+			return responseStruct, 0, err
+		}
 	}
 
 	response, err := httpClient.Do(request)
 	if err != nil {
-		log.Fatal(err)
+		return responseStruct, 0, &TransportError{Url: url, Err: err}
 	}
 	defer func(Body io.ReadCloser) {
 		err := Body.Close()
 		if err != nil {
-			log.Fatal(err)
+			log.Println(err)
 		}
 	}(response.Body)
 
-	validStatusCodes := []int{http.StatusOK, http.StatusNotFound, http.StatusGatewayTimeout, http.StatusTooManyRequests}
-	if !slices.Contains(validStatusCodes, response.StatusCode) {
-		log.Fatal(response.Status)
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return responseStruct, response.StatusCode, &TransportError{Url: url, Err: err}
+	}
+
+	// server errors back off like throttling because the retries of an api that is down would only add to its load
+	if limiter != nil {
+		if IsThrottled(response.StatusCode) || response.StatusCode >= http.StatusInternalServerError {
+			limiter.Throttled(getRetryAfter(response))
+		} else {
+			limiter.Succeeded()
+		}
+	}
+
+	if response.StatusCode == http.StatusNotFound {
+		return responseStruct, response.StatusCode, nil
 	}
 
 	if response.StatusCode != http.StatusOK {
-		return responseStruct, response.StatusCode
+		return responseStruct, response.StatusCode, &StatusError{
+			Url:        url,
+			StatusCode: response.StatusCode,
+			Status:     response.Status,
+			Body:       bodySnippet(body),
+			RetryAfter: getRetryAfter(response),
+		}
 	}
 
-	body, err := io.ReadAll(response.Body)
+	// the body is archived before it's decoded so that responses that don't match the types can be ingested again after
+	// the types are fixed
+	if archive != nil {
+		archive.Save(url, data, body)
+	}
+
+	err = json.Unmarshal(body, &responseStruct)
 	if err != nil {
-		log.Fatal(err)
+		return responseStruct, response.StatusCode, &DecodeError{Url: url, Err: err, Body: bodySnippet(body)}
+	}
+
+	return responseStruct, response.StatusCode, nil
+}
+
+func replayCall[T any](url string, data url.Values, responseStruct T) (T, int, error) {
+	body, isArchived := replay.Find(url, data)
+	if !isArchived {
+		return responseStruct, http.StatusNotFound, nil
+	}
+
+	err := json.Unmarshal(body, &responseStruct)
+	if err != nil {
+		return responseStruct, http.StatusOK, &DecodeError{Url: url, Err: err, Body: bodySnippet(body)}
+	}
+
+	return responseStruct, http.StatusOK, nil
+}
+
+// IsThrottled is true for the status codes the apis use when too many calls are made
+func IsThrottled(statusCode int) bool {
+	return statusCode == http.StatusTooManyRequests || statusCode == http.StatusGatewayTimeout
+}
+
+func getRetryAfter(response *http.Response) time.Duration {
+	return parseRetryAfter(response.Header.Get("Retry-After"), time.Now())
+}
+
+// parseRetryAfter reads the Retry-After header which can be a number of seconds or a date, it's 0 if the header is
+// missing, invalid or in the past
+func parseRetryAfter(retryAfter string, now time.Time) time.Duration {
+	if retryAfter == "" {
+		return 0
+	}
+
+	seconds, err := strconv.Atoi(retryAfter)
+	if err == nil {
+		return max(time.Duration(seconds)*time.Second, 0)
 	}
 
-	jsonError := json.Unmarshal(body, &responseStruct)
-	if jsonError != nil {
-		log.Fatal(jsonError)
+	date, err := http.ParseTime(retryAfter)
+	if err == nil {
+		return max(date.Sub(now), 0)
 	}
 
-	return responseStruct, response.StatusCode
+	return 0
 }
 
-func createGetRequest(url string, data url.Values, headers map[string]string) *http.Request {
-	request, err := http.NewRequest(http.MethodGet, url, nil)
+func createGetRequest(ctx context.Context, url string, data url.Values, headers map[string]string) (*http.Request, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 
 	request.Header.Add("Accept", "application/json")
@@ -73,10 +206,10 @@ func createGetRequest(url string, data url.Values, headers map[string]string) *h
 
 	request.URL.RawQuery = data.Encode()
 
-	return request
+	return request, nil
 }
 
-func createPostRequest(data url.Values, url string, headers map[string]string) *http.Request {
+func createPostRequest(ctx context.Context, data url.Values, url string, headers map[string]string) (*http.Request, error) {
 	var bodyString []string
 	for key, values := range data {
 		bodyString = append(bodyString, key+"="+strings.Join(values, ","))
@@ -85,9 +218,9 @@ func createPostRequest(data url.Values, url string, headers map[string]string) *
 	body := []byte(strings.Join(bodyString, "&"))
 	bodyBuffer := bytes.NewBuffer(body)
 
-	request, err := http.NewRequest(http.MethodPost, url, bodyBuffer)
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bodyBuffer)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 
 	request.Header.Add("Accept", "application/json")
@@ -96,5 +229,5 @@ func createPostRequest(data url.Values, url string, headers map[string]string) *
 		request.Header.Add(key, value)
 	}
 
-	return request
+	return request, nil
 }
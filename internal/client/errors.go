@@ -0,0 +1,57 @@
+package client
+
+import (
+	"fmt"
+	"time"
+)
+
+// maxBodySnippetLength is how much of a response body is kept in errors, enough to see the api error message without
+// flooding the logs with a whole page of results
+const maxBodySnippetLength = 500
+
+// TransportError is returned when the request could not be sent or the response could not be read
+type TransportError struct {
+	Url string
+	Err error
+}
+
+func (e *TransportError) Error() string {
+	return fmt.Sprintf("request to %v failed: %v", e.Url, e.Err)
+}
+
+func (e *TransportError) Unwrap() error {
+	return e.Err
+}
+
+// StatusError is returned when the api responds with a status code other than 200 or 404
+type StatusError struct {
+	Url        string
+	StatusCode int
+	Status     string
+	Body       string
+	// RetryAfter is how long the api asked to wait before calling again, 0 if it didn't say
+	RetryAfter time.Duration
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("request to %v returned %v: %v", e.Url, e.Status, e.Body)
+}
+
+// DecodeError is returned when the response body is not the json that was expected
+type DecodeError struct {
+	Url  string
+	Err  error
+	Body string
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("response from %v could not be decoded: %v: %v", e.Url, e.Err, e.Body)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+func bodySnippet(body []byte) string {
+	return fmt.Sprintf("%.*s", maxBodySnippetLength, body)
+}
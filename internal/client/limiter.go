@@ -0,0 +1,143 @@
+package client
+
+import (
+	"context"
+	"math"
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+const minBackoff = time.Second
+
+// minCallsPerSecond is the lowest rate the limiter slows down to while the api keeps throttling
+const minCallsPerSecond = 0.1
+
+// Limiter is a token bucket that spaces calls evenly so that the calls per second limit is never overshot. When the api
+// throttles, all calls are paused for the time the api asks for or an exponential backoff, and the rate is halved. The
+// rate then recovers slowly with every successful call.
+type Limiter struct {
+	mutex       sync.Mutex
+	maxRate     float64
+	rate        float64
+	tokens      float64
+	lastRefill  time.Time
+	pausedUntil time.Time
+	failures    int
+	maxBackoff  time.Duration
+	clock       clock
+}
+
+// clock is replaced in tests so that waits don't take real time
+type clock interface {
+	Now() time.Time
+	Sleep(ctx context.Context, duration time.Duration) error
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) Sleep(ctx context.Context, duration time.Duration) error {
+	return sleep(ctx, duration)
+}
+
+func NewLimiter(callsPerSecond int, maxBackoff time.Duration) *Limiter {
+	return newLimiter(callsPerSecond, maxBackoff, realClock{})
+}
+
+func newLimiter(callsPerSecond int, maxBackoff time.Duration, clock clock) *Limiter {
+	return &Limiter{
+		maxRate:    float64(callsPerSecond),
+		rate:       float64(callsPerSecond),
+		tokens:     1,
+		lastRefill: clock.Now(),
+		maxBackoff: max(maxBackoff, minBackoff),
+		clock:      clock,
+	}
+}
+
+// Wait blocks until a call can be made or the context is cancelled
+func (l *Limiter) Wait(ctx context.Context) error {
+	for {
+		l.mutex.Lock()
+
+		now := l.clock.Now()
+		var wait time.Duration
+		if now.Before(l.pausedUntil) {
+			wait = l.pausedUntil.Sub(now)
+		} else {
+			l.refill(now)
+			if l.tokens >= 1 {
+				l.tokens--
+				l.mutex.Unlock()
+				return nil
+			}
+
+			wait = time.Duration((1 - l.tokens) / l.rate * float64(time.Second))
+		}
+
+		l.mutex.Unlock()
+
+		err := l.clock.Sleep(ctx, wait)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// Throttled pauses all calls and slows down the rate, it's called when the api throttles or fails with a server error.
+// retryAfter is the wait the api asked for, 0 if it didn't.
+func (l *Limiter) Throttled(retryAfter time.Duration) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.failures++
+	l.rate = max(l.rate/2, minCallsPerSecond)
+
+	wait := max(retryAfter, l.backoff())
+	pausedUntil := l.clock.Now().Add(wait)
+	if pausedUntil.After(l.pausedUntil) {
+		l.pausedUntil = pausedUntil
+	}
+}
+
+// Succeeded resets the backoff and brings the rate back up, it's increased slowly so that the limiter doesn't go
+// straight back to the rate that got it throttled
+func (l *Limiter) Succeeded() {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.failures = 0
+	l.rate = min(l.rate+l.maxRate/50, l.maxRate)
+}
+
+func sleep(ctx context.Context, duration time.Duration) error {
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (l *Limiter) refill(now time.Time) {
+	// the bucket only holds one token so calls are spread out evenly instead of being sent in bursts
+	l.tokens = min(l.tokens+now.Sub(l.lastRefill).Seconds()*l.rate, 1)
+	l.lastRefill = now
+}
+
+// backoff doubles with every consecutive failure, the jitter keeps concurrent calls from retrying all at once
+func (l *Limiter) backoff() time.Duration {
+	backoff := time.Duration(float64(minBackoff) * math.Pow(2, float64(l.failures-1)))
+	if backoff <= 0 || backoff > l.maxBackoff {
+		backoff = l.maxBackoff
+	}
+
+	return backoff/2 + rand.N(backoff/2+1)
+}
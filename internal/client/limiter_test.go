@@ -0,0 +1,223 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock moves forward only when something sleeps on it
+type fakeClock struct {
+	mutex sync.Mutex
+	now   time.Time
+	slept time.Duration
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.now
+}
+
+func (c *fakeClock) Sleep(ctx context.Context, duration time.Duration) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.now = c.now.Add(duration)
+	c.slept += duration
+
+	return nil
+}
+
+func (c *fakeClock) Slept() time.Duration {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.slept
+}
+
+func TestLimiterSpacesCalls(t *testing.T) {
+	tests := []struct {
+		name           string
+		callsPerSecond int
+		calls          int
+		wantSlept      time.Duration
+	}{
+		{"first call is not delayed", 2, 1, 0},
+		{"calls are spaced by the rate", 2, 5, 2 * time.Second},
+		{"one call per second", 1, 3, 2 * time.Second},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			clock := newFakeClock()
+			limiter := newLimiter(test.callsPerSecond, time.Minute, clock)
+
+			for range test.calls {
+				err := limiter.Wait(context.Background())
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if !isAbout(clock.Slept(), test.wantSlept) {
+				t.Errorf("slept %v, want %v", clock.Slept(), test.wantSlept)
+			}
+		})
+	}
+}
+
+func TestLimiterThrottled(t *testing.T) {
+	tests := []struct {
+		name       string
+		throttles  int
+		retryAfter time.Duration
+		maxBackoff time.Duration
+		minWait    time.Duration
+		maxWait    time.Duration
+		wantRate   float64
+	}{
+		{"first backoff", 1, 0, time.Minute, 500 * time.Millisecond, time.Second, 5},
+		{"backoff doubles", 3, 0, time.Minute, 2 * time.Second, 4 * time.Second, 1.25},
+		{"backoff is capped", 10, 0, 8 * time.Second, 4 * time.Second, 8 * time.Second, minCallsPerSecond},
+		{"retry after is respected", 1, 30 * time.Second, time.Minute, 30 * time.Second, 30 * time.Second, 5},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			clock := newFakeClock()
+			limiter := newLimiter(10, test.maxBackoff, clock)
+
+			for range test.throttles {
+				limiter.Throttled(test.retryAfter)
+			}
+
+			err := limiter.Wait(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if clock.Slept() < test.minWait || clock.Slept() > test.maxWait {
+				t.Errorf("slept %v, want between %v and %v", clock.Slept(), test.minWait, test.maxWait)
+			}
+			if limiter.rate != test.wantRate {
+				t.Errorf("rate = %v, want %v", limiter.rate, test.wantRate)
+			}
+		})
+	}
+}
+
+func TestLimiterSucceededRecovers(t *testing.T) {
+	limiter := newLimiter(10, time.Minute, newFakeClock())
+	limiter.Throttled(0)
+	limiter.Throttled(0)
+
+	limiter.Succeeded()
+	if limiter.failures != 0 {
+		t.Errorf("failures = %v, want 0", limiter.failures)
+	}
+	if limiter.rate != 2.7 {
+		t.Errorf("rate = %v, want 2.7", limiter.rate)
+	}
+
+	for range 100 {
+		limiter.Succeeded()
+	}
+	if limiter.rate != 10 {
+		t.Errorf("rate = %v, want it back to 10", limiter.rate)
+	}
+}
+
+func TestLimiterWaitCancelled(t *testing.T) {
+	limiter := newLimiter(1, time.Minute, newFakeClock())
+	limiter.Throttled(time.Hour)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := limiter.Wait(ctx)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, want context.Canceled", err)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		retryAfter string
+		want       time.Duration
+	}{
+		{"missing", "", 0},
+		{"seconds", "120", 2 * time.Minute},
+		{"negative seconds", "-5", 0},
+		{"date", "Mon, 01 Jan 2024 00:00:30 GMT", 30 * time.Second},
+		{"date in the past", "Sun, 31 Dec 2023 23:59:00 GMT", 0},
+		{"invalid", "soon", 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := parseRetryAfter(test.retryAfter, now)
+			if got != test.want {
+				t.Errorf("parseRetryAfter(%q) = %v, want %v", test.retryAfter, got, test.want)
+			}
+		})
+	}
+}
+
+func TestCallBacksOffOnServerErrors(t *testing.T) {
+	tests := []struct {
+		name          string
+		statusCode    int
+		wantThrottled bool
+	}{
+		{"ok", http.StatusOK, false},
+		{"not found", http.StatusNotFound, false},
+		{"too many requests", http.StatusTooManyRequests, true},
+		{"internal server error", http.StatusInternalServerError, true},
+		{"service unavailable", http.StatusServiceUnavailable, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+				writer.WriteHeader(test.statusCode)
+				_, _ = writer.Write([]byte(`{}`))
+			}))
+			defer server.Close()
+
+			testLimiter := newLimiter(10, time.Minute, newFakeClock())
+			SetLimiter(testLimiter)
+			defer SetLimiter(nil)
+
+			_, _, _ = Call(context.Background(), "get", server.URL, nil, nil, struct{}{})
+
+			isThrottled := testLimiter.failures > 0
+			if isThrottled != test.wantThrottled {
+				t.Errorf("throttled = %v, want %v", isThrottled, test.wantThrottled)
+			}
+			if isThrottled && testLimiter.rate >= 10 {
+				t.Errorf("rate = %v, want it lowered", testLimiter.rate)
+			}
+		})
+	}
+}
+
+func isAbout(duration time.Duration, want time.Duration) bool {
+	return duration >= want-time.Millisecond && duration <= want+time.Millisecond
+}
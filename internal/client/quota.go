@@ -0,0 +1,92 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// QuotaStore persists the number of calls made per day so that restarts don't reset the daily quota
+type QuotaStore interface {
+	GetCalls(day string) int
+	AddCall(day string)
+}
+
+// QuotaExceededError is returned by calls made after the daily quota was used up when the quota is not set to wait
+type QuotaExceededError struct {
+	ResetAt time.Time
+}
+
+func (e *QuotaExceededError) Error() string {
+	return fmt.Sprintf("daily call quota used up, it resets at %v", e.ResetAt.Format(time.RFC3339))
+}
+
+// Quota counts calls per UTC day, which is when the apis reset their daily limits
+type Quota struct {
+	mutex          sync.Mutex
+	maxCallsPerDay int
+	shouldWait     bool
+	store          QuotaStore
+	clock          clock
+	day            string
+	calls          int
+}
+
+func NewQuota(maxCallsPerDay int, shouldWait bool, store QuotaStore) *Quota {
+	return newQuota(maxCallsPerDay, shouldWait, store, realClock{})
+}
+
+func newQuota(maxCallsPerDay int, shouldWait bool, store QuotaStore, clock clock) *Quota {
+	return &Quota{
+		maxCallsPerDay: maxCallsPerDay,
+		shouldWait:     shouldWait,
+		store:          store,
+		clock:          clock,
+	}
+}
+
+// Take counts a call, if the quota is used up it either waits until it resets or returns a QuotaExceededError
+func (q *Quota) Take(ctx context.Context) error {
+	for {
+		resetAt, err := q.take()
+		if err != nil || resetAt.IsZero() {
+			return err
+		}
+
+		// the lock is released while waiting so that the other calls can give up when their context is cancelled. They
+		// see the quota used up and wait as well.
+		log.Printf("Daily call quota used up, waiting until %v\n", resetAt.Format(time.RFC3339))
+		err = q.clock.Sleep(ctx, resetAt.Sub(q.clock.Now()))
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// take counts a call if the quota is not used up, otherwise it returns when the quota resets
+func (q *Quota) take() (time.Time, error) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	now := q.clock.Now().UTC()
+	day := now.Format(time.DateOnly)
+	if day != q.day {
+		q.day = day
+		q.calls = q.store.GetCalls(day)
+	}
+
+	if q.calls < q.maxCallsPerDay {
+		q.calls++
+		q.store.AddCall(day)
+		return time.Time{}, nil
+	}
+
+	resetAt := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC)
+	if !q.shouldWait {
+		return time.Time{}, &QuotaExceededError{ResetAt: resetAt}
+	}
+
+	return resetAt, nil
+}
@@ -0,0 +1,162 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+type memoryQuotaStore struct {
+	mutex sync.Mutex
+	calls map[string]int
+}
+
+func newMemoryQuotaStore() *memoryQuotaStore {
+	return &memoryQuotaStore{calls: make(map[string]int)}
+}
+
+func (s *memoryQuotaStore) GetCalls(day string) int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.calls[day]
+}
+
+func (s *memoryQuotaStore) AddCall(day string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.calls[day]++
+}
+
+// blockingClock never moves forward, sleeping on it only ends when the context is cancelled
+type blockingClock struct {
+	sleeping chan struct{}
+}
+
+func (c blockingClock) Now() time.Time {
+	return time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+}
+
+func (c blockingClock) Sleep(ctx context.Context, duration time.Duration) error {
+	c.sleeping <- struct{}{}
+	<-ctx.Done()
+
+	return ctx.Err()
+}
+
+func TestQuotaTake(t *testing.T) {
+	tests := []struct {
+		name        string
+		savedCalls  int
+		calls       int
+		wantErrors  int
+		wantResetAt time.Time
+	}{
+		{"under the quota", 0, 3, 0, time.Time{}},
+		{"over the quota", 0, 5, 2, time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)},
+		{"saved calls count", 2, 3, 2, time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			store := newMemoryQuotaStore()
+			store.calls["2024-01-01"] = test.savedCalls
+			quota := newQuota(3, false, store, newFakeClock())
+
+			errorCount := 0
+			for range test.calls {
+				err := quota.Take(context.Background())
+
+				var quotaExceededError *QuotaExceededError
+				if errors.As(err, &quotaExceededError) {
+					errorCount++
+					if !quotaExceededError.ResetAt.Equal(test.wantResetAt) {
+						t.Errorf("reset at %v, want %v", quotaExceededError.ResetAt, test.wantResetAt)
+					}
+				} else if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if errorCount != test.wantErrors {
+				t.Errorf("got %v errors, want %v", errorCount, test.wantErrors)
+			}
+			if store.calls["2024-01-01"] > 3 {
+				t.Errorf("saved %v calls, want at most 3", store.calls["2024-01-01"])
+			}
+		})
+	}
+}
+
+func TestQuotaWaitsForTheNextDay(t *testing.T) {
+	store := newMemoryQuotaStore()
+	clock := newFakeClock()
+	quota := newQuota(1, true, store, clock)
+
+	for range 2 {
+		err := quota.Take(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if clock.Slept() != 24*time.Hour {
+		t.Errorf("slept %v, want 24h", clock.Slept())
+	}
+	if store.calls["2024-01-01"] != 1 || store.calls["2024-01-02"] != 1 {
+		t.Errorf("saved calls = %v, want one on each day", store.calls)
+	}
+}
+
+func TestQuotaWaitDoesNotBlockCancelledCalls(t *testing.T) {
+	clock := blockingClock{sleeping: make(chan struct{}, 2)}
+	quota := newQuota(0, true, newMemoryQuotaStore(), clock)
+
+	waitingCtx, cancelWaiting := context.WithCancel(context.Background())
+	defer cancelWaiting()
+	go func() {
+		_ = quota.Take(waitingCtx)
+	}()
+	<-clock.sleeping
+
+	// a call made while another one waits for the quota can still give up
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	err := quota.Take(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v, want context.DeadlineExceeded", err)
+	}
+}
+
+func TestCallCancelledWhileWaitingDoesNotUseQuota(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		_, _ = writer.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	testLimiter := newLimiter(1, time.Minute, newFakeClock())
+	testLimiter.Throttled(time.Hour)
+	SetLimiter(testLimiter)
+	defer SetLimiter(nil)
+
+	store := newMemoryQuotaStore()
+	SetQuota(newQuota(10, false, store, newFakeClock()))
+	defer SetQuota(nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, _, err := Call(ctx, "get", server.URL, nil, nil, struct{}{})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, want context.Canceled", err)
+	}
+	if store.calls["2024-01-01"] != 0 {
+		t.Errorf("saved %v calls, want 0", store.calls["2024-01-01"])
+	}
+}
@@ -20,25 +20,52 @@ type Config struct {
 	IsbndbApiKey                string
 	IsbndbApiUrl                string
 	CallsPerSecond              int
+	MaxCallsPerDay              int
+	OnQuotaExceeded             string
 	TimeoutSeconds              int
+	DbDriver                    string
+	DbFile                      string
 	DbHost                      string
 	DbPort                      string
 	DbUsername                  string
 	DbPassword                  string
+	DbName                      string
 	DbNameBooks                 string
 	DbNameProgress              string
 	DbConcurrentWriteGoroutines int
-	// todo: implement MaxCallsPerDay
+	RecheckNotFoundDays         int
+	Refresh                     bool
+	WatchPrices                 bool
+	ArchiveResponses            bool
 	// todo: move google api url to here
 }
 
 func Get() Config {
+	config := parse()
+	validateConfiguration(config)
+
+	return config
+}
+
+// GetDatabase is for the utilities that only need to connect to the database
+func GetDatabase() Config {
+	config := parse()
+	validateDatabaseConfiguration(config)
+
+	return config
+}
+
+func parse() Config {
 	var config Config
 
 	callsPerSecond, err := strconv.Atoi(os.Getenv("CALLS_PER_SECOND"))
 	if err != nil {
 		callsPerSecond = 0
 	}
+	maxCallsPerDay, err := strconv.Atoi(os.Getenv("MAX_CALLS_PER_DAY"))
+	if err != nil {
+		maxCallsPerDay = 0
+	}
 	timeoutSeconds, err := strconv.Atoi(os.Getenv("TIMEOUT_SECONDS"))
 	if err != nil {
 		timeoutSeconds = 0
@@ -47,21 +74,48 @@ func Get() Config {
 	if err != nil {
 		dbConcurrentWriteGoroutines = 0
 	}
+	recheckNotFoundDays, err := strconv.Atoi(os.Getenv("RECHECK_NOT_FOUND_DAYS"))
+	if err != nil {
+		recheckNotFoundDays = 0
+	}
+	refresh, err := strconv.ParseBool(os.Getenv("REFRESH"))
+	if err != nil {
+		refresh = false
+	}
+	watchPrices, err := strconv.ParseBool(os.Getenv("WATCH_PRICES"))
+	if err != nil {
+		watchPrices = false
+	}
+	archiveResponses, err := strconv.ParseBool(os.Getenv("ARCHIVE_RESPONSES"))
+	if err != nil {
+		archiveResponses = true
+	}
 
-	config.SearchBy = *flag.String("search-by", os.Getenv("SEARCH_BY"), "Title, subject or isbn.")
-	config.File = *flag.String("file", os.Getenv("FILE"), "File to read from.")
-	config.Provider = *flag.String("provider", os.Getenv("PROVIDER"), "IsbnDB or Google.")
-	config.IsbndbSubscriptionType = *flag.String("isbndb-subscription-type", os.Getenv("ISBNDB_SUBSCRIPTION_TYPE"), "Basic, premium or pro. Required if provider is IsbnDB.")
-	config.IsbndbApiKey = *flag.String("isbndb-api-key", os.Getenv("ISBNDB_API_KEY"), "IsbnDB API key. Required if provider is IsbnDB.")
-	config.CallsPerSecond = *flag.Int("calls-per-second", callsPerSecond, "The max number of calls per second that should be made to the API.")
-	config.TimeoutSeconds = *flag.Int("timeout-seconds", timeoutSeconds, "If the API returns a timeout, how many seconds should be waited before trying again.")
-	config.DbHost = *flag.String("db-host", os.Getenv("DB_HOST"), "Database host.")
-	config.DbPort = *flag.String("db-port", os.Getenv("DB_PORT"), "Database port.")
-	config.DbUsername = *flag.String("db-username", os.Getenv("DB_USERNAME"), "Database username.")
-	config.DbPassword = *flag.String("db-password", os.Getenv("DB_PASSWORD"), "Database password.")
-	config.DbNameBooks = *flag.String("db-name-books", os.Getenv("DB_NAME_BOOKS"), "The name of the database where books are saved.")
-	config.DbNameProgress = *flag.String("db-name-progress", os.Getenv("DB_NAME_PROGRESS"), "The name of the database where progress is saved.")
-	config.DbConcurrentWriteGoroutines = *flag.Int("db-concurrent-write-goroutines", dbConcurrentWriteGoroutines, "How many goroutines should be used to write to the database. You should be mindful of how many concurrent threads your database can handle.")
+	flag.StringVar(&config.SearchBy, "search-by", os.Getenv("SEARCH_BY"), "Title, subject or isbn.")
+	flag.StringVar(&config.File, "file", os.Getenv("FILE"), "File to read from. Can be a glob pattern to read multiple files, .gz and .zst files are decompressed and - reads from stdin.")
+	flag.StringVar(&config.Provider, "provider", os.Getenv("PROVIDER"), "IsbnDB, Google or OpenLibrary.")
+	flag.StringVar(&config.IsbndbSubscriptionType, "isbndb-subscription-type", os.Getenv("ISBNDB_SUBSCRIPTION_TYPE"), "Basic, premium or pro. Required if provider is IsbnDB.")
+	flag.StringVar(&config.IsbndbApiKey, "isbndb-api-key", os.Getenv("ISBNDB_API_KEY"), "IsbnDB API key. Required if provider is IsbnDB.")
+	flag.IntVar(&config.CallsPerSecond, "calls-per-second", callsPerSecond, "The max number of calls per second that should be made to the API. Defaults to the max the provider allows.")
+	flag.IntVar(&config.MaxCallsPerDay, "max-calls-per-day", maxCallsPerDay, "The max number of calls per UTC day that should be made to the API. Defaults to the max the provider allows.")
+	flag.StringVar(&config.OnQuotaExceeded, "on-quota-exceeded", os.Getenv("ON_QUOTA_EXCEEDED"), "Wait or exit. What to do when the max number of calls per day is reached.")
+	flag.IntVar(&config.TimeoutSeconds, "timeout-seconds", timeoutSeconds, "If the API keeps returning timeouts, the max number of seconds that should be waited before trying again.")
+	flag.StringVar(&config.DbDriver, "db-driver", os.Getenv("DB_DRIVER"), "Mysql, postgres or sqlite.")
+	flag.StringVar(&config.DbFile, "db-file", os.Getenv("DB_FILE"), "The file where books and progress are saved if the database driver is sqlite.")
+	flag.StringVar(&config.DbHost, "db-host", os.Getenv("DB_HOST"), "Database host.")
+	flag.StringVar(&config.DbPort, "db-port", os.Getenv("DB_PORT"), "Database port.")
+	flag.StringVar(&config.DbUsername, "db-username", os.Getenv("DB_USERNAME"), "Database username.")
+	flag.StringVar(&config.DbPassword, "db-password", os.Getenv("DB_PASSWORD"), "Database password.")
+	flag.StringVar(&config.DbName, "db-name", os.Getenv("DB_NAME"), "The database where the books and progress schemas are created if the database driver is postgres.")
+	flag.StringVar(&config.DbNameBooks, "db-name-books", os.Getenv("DB_NAME_BOOKS"), "The name of the database where books are saved.")
+	flag.StringVar(&config.DbNameProgress, "db-name-progress", os.Getenv("DB_NAME_PROGRESS"), "The name of the database where progress is saved.")
+	flag.IntVar(&config.DbConcurrentWriteGoroutines, "db-concurrent-write-goroutines", dbConcurrentWriteGoroutines, "How many goroutines should be used to write to the database. You should be mindful of how many concurrent threads your database can handle.")
+
+	flag.IntVar(&config.RecheckNotFoundDays, "recheck-not-found-days", recheckNotFoundDays, "When searching by isbn, how many days old an isbn that was not found should be before it is searched again. Not found isbns are never searched again if not set.")
+
+	flag.BoolVar(&config.Refresh, "refresh", refresh, "Look up the saved books again by their id and update the ones that changed instead of reading queries from the file.")
+	flag.BoolVar(&config.WatchPrices, "watch-prices", watchPrices, "Search every isbn of the file on every run, even the ones that were searched before, and save their current prices. Only available for IsbnDB with the pro subscription.")
+	flag.BoolVar(&config.ArchiveResponses, "archive-responses", archiveResponses, "Save the raw api responses compressed to the progress database so that they can be ingested again with the reingest utility. Enabled by default.")
 
 	flag.Parse()
 
@@ -69,12 +123,32 @@ func Get() Config {
 		config.SearchBy = "title"
 	}
 
+	if config.Refresh || config.WatchPrices {
+		config.SearchBy = "isbn"
+	}
+
 	if config.Provider == "" {
 		config.Provider = "isbndb"
 	}
 
-	if config.CallsPerSecond == 0 {
-		config.CallsPerSecond = 1
+	if config.OnQuotaExceeded == "" {
+		config.OnQuotaExceeded = "wait"
+	}
+
+	if config.TimeoutSeconds == 0 {
+		config.TimeoutSeconds = 60
+	}
+
+	if config.DbDriver == "" {
+		config.DbDriver = "mysql"
+	}
+
+	if config.DbFile == "" {
+		config.DbFile = "book_data_" + config.Provider + ".sqlite"
+	}
+
+	if config.DbName == "" {
+		config.DbName = "postgres"
 	}
 
 	if config.DbNameBooks == "" {
@@ -89,8 +163,6 @@ func Get() Config {
 		config.DbConcurrentWriteGoroutines = 1
 	}
 
-	validateConfiguration(config)
-
 	isbndbApiUrls := map[string]string{
 		"basic":   apiUrlBasic,
 		"premium": apiUrlPremium,
@@ -107,14 +179,11 @@ func validateConfiguration(config Config) {
 		log.Fatal("Invalid search by value")
 	}
 
-	if config.File == "" {
+	if config.File == "" && !config.Refresh {
 		log.Fatal("File is not set")
 	}
 
-	validProviderValues := []string{"isbndb", "google"}
-	if !slices.Contains(validProviderValues, config.Provider) {
-		log.Fatal("Invalid provider value")
-	}
+	validateDatabaseConfiguration(config)
 
 	validIsbndbSubscriptionTypeValues := []string{"basic", "premium", "pro"}
 	if config.Provider == "isbndb" && !slices.Contains(validIsbndbSubscriptionTypeValues, config.IsbndbSubscriptionType) {
@@ -125,28 +194,54 @@ func validateConfiguration(config Config) {
 		log.Fatal("IsbnDB API key is not set")
 	}
 
-	if config.CallsPerSecond < 1 {
+	if config.CallsPerSecond < 0 {
 		log.Fatal("Invalid calls per second value")
 	}
 
-	if config.TimeoutSeconds < 0 {
+	if config.MaxCallsPerDay < 0 {
+		log.Fatal("Invalid max calls per day value")
+	}
+
+	validOnQuotaExceededValues := []string{"wait", "exit"}
+	if !slices.Contains(validOnQuotaExceededValues, config.OnQuotaExceeded) {
+		log.Fatal("Invalid on quota exceeded value")
+	}
+
+	if config.TimeoutSeconds < 1 {
 		log.Fatal("Invalid timeout seconds value")
 	}
 
-	if config.DbHost == "" {
-		log.Fatal("Database host is not set")
+	if config.DbConcurrentWriteGoroutines < 1 {
+		log.Fatal("Invalid database concurrent write goroutines value")
 	}
 
-	if config.DbPort == "" {
-		log.Fatal("Database port is not set")
+	if config.RecheckNotFoundDays < 0 {
+		log.Fatal("Invalid recheck not found days value")
 	}
 
-	if config.DbUsername == "" {
-		log.Fatal("Database username is not set")
+	if config.WatchPrices && (config.Provider != "isbndb" || config.IsbndbSubscriptionType != "pro") {
+		log.Fatal("Watching prices is only available for IsbnDB with the pro subscription")
 	}
 
-	if config.DbPassword == "" {
-		log.Fatal("Database password is not set")
+	if config.WatchPrices && config.Refresh {
+		log.Fatal("Watching prices and refreshing can't be used at the same time")
+	}
+}
+
+func validateDatabaseConfiguration(config Config) {
+	validProviderValues := []string{"isbndb", "google", "openlibrary"}
+	if !slices.Contains(validProviderValues, config.Provider) {
+		log.Fatal("Invalid provider value")
+	}
+
+	validDbDriverValues := []string{"mysql", "postgres", "sqlite"}
+	if !slices.Contains(validDbDriverValues, config.DbDriver) {
+		log.Fatal("Invalid database driver value")
+	}
+
+	// sqlite only needs a file
+	if config.DbDriver != "sqlite" {
+		validateDatabaseServerConfiguration(config)
 	}
 
 	if config.DbNameBooks == "" {
@@ -156,8 +251,22 @@ func validateConfiguration(config Config) {
 	if config.DbNameProgress == "" {
 		log.Fatal("Database name progress is not set")
 	}
+}
 
-	if config.DbConcurrentWriteGoroutines < 1 {
-		log.Fatal("Invalid database concurrent write goroutines value")
+func validateDatabaseServerConfiguration(config Config) {
+	if config.DbHost == "" {
+		log.Fatal("Database host is not set")
+	}
+
+	if config.DbPort == "" {
+		log.Fatal("Database port is not set")
+	}
+
+	if config.DbUsername == "" {
+		log.Fatal("Database username is not set")
+	}
+
+	if config.DbPassword == "" {
+		log.Fatal("Database password is not set")
 	}
 }
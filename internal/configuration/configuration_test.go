@@ -0,0 +1,61 @@
+package configuration
+
+import (
+	"flag"
+	"os"
+	"testing"
+)
+
+// parseArgs parses the command line arguments with a new flag set so that the flags can be defined again
+func parseArgs(t *testing.T, args ...string) Config {
+	previousArgs := os.Args
+	previousCommandLine := flag.CommandLine
+	t.Cleanup(func() {
+		os.Args = previousArgs
+		flag.CommandLine = previousCommandLine
+	})
+
+	os.Args = append([]string{"collector"}, args...)
+	flag.CommandLine = flag.NewFlagSet("collector", flag.ContinueOnError)
+
+	return parse()
+}
+
+func TestParseFlags(t *testing.T) {
+	t.Setenv("PROVIDER", "isbndb")
+	t.Setenv("DB_DRIVER", "mysql")
+	t.Setenv("CALLS_PER_SECOND", "1")
+
+	config := parseArgs(t, "-provider", "openlibrary", "-db-driver", "sqlite", "-calls-per-second", "5", "-refresh", "-file", "isbns.txt")
+
+	if config.Provider != "openlibrary" {
+		t.Errorf("provider = %q, want the flag value", config.Provider)
+	}
+	if config.DbDriver != "sqlite" {
+		t.Errorf("db driver = %q, want the flag value", config.DbDriver)
+	}
+	if config.CallsPerSecond != 5 {
+		t.Errorf("calls per second = %v, want the flag value", config.CallsPerSecond)
+	}
+	if !config.Refresh || config.SearchBy != "isbn" {
+		t.Errorf("refresh = %v and search by = %q, want a refresh by isbn", config.Refresh, config.SearchBy)
+	}
+	if config.File != "isbns.txt" {
+		t.Errorf("file = %q, want the flag value", config.File)
+	}
+	if config.DbNameBooks != "book_data_openlibrary" {
+		t.Errorf("books database = %q, want the default of the flag provider", config.DbNameBooks)
+	}
+}
+
+func TestParseEnvironment(t *testing.T) {
+	t.Setenv("PROVIDER", "google")
+	t.Setenv("DB_DRIVER", "postgres")
+	t.Setenv("CALLS_PER_SECOND", "3")
+
+	config := parseArgs(t)
+
+	if config.Provider != "google" || config.DbDriver != "postgres" || config.CallsPerSecond != 3 {
+		t.Errorf("config = %+v, want the environment values", config)
+	}
+}
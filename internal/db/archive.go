@@ -0,0 +1,154 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"net/url"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// responseEncoder and responseDecoder are shared because they are safe for concurrent use and expensive to create
+var responseEncoder = newResponseEncoder()
+var responseDecoder = newResponseDecoder()
+
+// ApiResponse is an archived response body of one of the provider's endpoints
+type ApiResponse struct {
+	Id         int
+	Endpoint   string
+	Parameters string
+	Body       []byte
+	CreatedAt  time.Time
+}
+
+// ResponseArchive saves the raw api responses of a provider to the progress database compressed, keyed by the
+// endpoint and the parameters of the request, which include the query and the page
+type ResponseArchive struct {
+	ctx        context.Context
+	progressDb *sql.DB
+	provider   string
+}
+
+func NewResponseArchive(ctx context.Context, progressDb *sql.DB, provider string) ResponseArchive {
+	return ResponseArchive{ctx: ctx, progressDb: progressDb, provider: provider}
+}
+
+func (archive ResponseArchive) Save(requestUrl string, data url.Values, body []byte) {
+	_, err := archive.progressDb.ExecContext(
+		archive.ctx,
+		rebind(`INSERT INTO api_responses (provider, endpoint, parameters, body, created_at) VALUES (?, ?, ?, ?, ?)`),
+		archive.provider,
+		getEndpoint(requestUrl),
+		data.Encode(),
+		responseEncoder.EncodeAll(body, nil),
+		time.Now().UTC(),
+	)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func (archive ResponseArchive) Find(requestUrl string, data url.Values) ([]byte, bool) {
+	var body []byte
+	err := archive.progressDb.QueryRowContext(
+		archive.ctx,
+		rebind(`SELECT body FROM api_responses WHERE provider = ? AND endpoint = ? AND parameters = ? ORDER BY id DESC LIMIT 1`),
+		archive.provider,
+		getEndpoint(requestUrl),
+		data.Encode(),
+	).Scan(&body)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, false
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	return decompressResponse(body), true
+}
+
+// GetApiResponses returns the archived responses of the provider after the id in the order they were saved, at most
+// limit of them
+func (archive ResponseArchive) GetApiResponses(afterId int, limit int) []ApiResponse {
+	rows, err := archive.progressDb.QueryContext(
+		archive.ctx,
+		rebind(`SELECT id, endpoint, parameters, body, created_at FROM api_responses WHERE provider = ? AND id > ? ORDER BY id LIMIT ?`),
+		archive.provider,
+		afterId,
+		limit,
+	)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			log.Fatal(err)
+		}
+	}(rows)
+
+	var responses []ApiResponse
+	for rows.Next() {
+		var response ApiResponse
+		err := rows.Scan(&response.Id, &response.Endpoint, &response.Parameters, &response.Body, &response.CreatedAt)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		response.Body = decompressResponse(response.Body)
+		responses = append(responses, response)
+	}
+
+	return responses
+}
+
+func (archive ResponseArchive) CountApiResponses() int {
+	var count int
+	err := archive.progressDb.QueryRowContext(archive.ctx, rebind(`SELECT COUNT(*) FROM api_responses WHERE provider = ?`), archive.provider).Scan(&count)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	return count
+}
+
+// getEndpoint is the path of the request url, the api url is not part of the key so that the archive still matches if
+// the api url is changed
+func getEndpoint(requestUrl string) string {
+	parsedUrl, err := url.Parse(requestUrl)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	return parsedUrl.Path
+}
+
+func decompressResponse(body []byte) []byte {
+	decompressed, err := responseDecoder.DecodeAll(body, nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	return decompressed
+}
+
+func newResponseEncoder() *zstd.Encoder {
+	encoder, err := zstd.NewWriter(nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	return encoder
+}
+
+func newResponseDecoder() *zstd.Decoder {
+	decoder, err := zstd.NewReader(nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	return decoder
+}
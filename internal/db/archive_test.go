@@ -0,0 +1,54 @@
+package db
+
+import (
+	"context"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/zaelmyth/book-data-collector/internal/provider"
+)
+
+func TestArchivedResponsesKeepWhenTheyWereReceived(t *testing.T) {
+	receivedAt := time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)
+
+	forEachDriver(t, func(t *testing.T, databases testDatabases) {
+		ctx := context.Background()
+		MigrateUp(ctx, databases.booksDb, databases.progressDb)
+
+		archive := NewResponseArchive(ctx, databases.progressDb, "isbndb")
+		archive.Save("https://api2.isbndb.com/book/9780261103344", url.Values{}, []byte(`{"book": {}}`))
+
+		_, err := databases.progressDb.Exec(rebind(`UPDATE api_responses SET created_at = ?`), receivedAt)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		responses := archive.GetApiResponses(0, 10)
+		if len(responses) != 1 {
+			t.Fatalf("got %v responses, want 1", len(responses))
+		}
+		if !responses[0].CreatedAt.Equal(receivedAt) {
+			t.Errorf("created at %v, want %v", responses[0].CreatedAt, receivedAt)
+		}
+		if string(responses[0].Body) != `{"book": {}}` {
+			t.Errorf("body = %q, want the saved body", responses[0].Body)
+		}
+
+		price := 9.99
+		book := hobbit
+		book.Prices = []provider.Price{{Merchant: "Bookshop", Currency: "USD", Price: &price}}
+
+		savedData := newTestSavedData(ctx, databases, "isbn13")
+		SaveBooksAt(ctx, databases.booksDb, "isbn13", []provider.Record{book}, savedData, true, responses[0].CreatedAt)
+
+		var collectedAt time.Time
+		err = databases.booksDb.QueryRow(`SELECT collected_at FROM book_prices`).Scan(&collectedAt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !collectedAt.Equal(receivedAt) {
+			t.Errorf("price collected at %v, want %v", collectedAt, receivedAt)
+		}
+	})
+}
@@ -0,0 +1,126 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+
+	"github.com/zaelmyth/book-data-collector/internal/authorname"
+)
+
+// NormalizeAuthors returns how many authors were merged and how many book links were moved
+func NormalizeAuthors(ctx context.Context, db *sql.DB) (int, int) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	rows, err := tx.QueryContext(ctx, `SELECT id, name, name_key FROM authors ORDER BY id`)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	groups := make(map[string][]int)
+	names := make(map[int]string)
+	savedKeys := make(map[int]string)
+	var id int
+	var name string
+	var savedKey sql.NullString
+	for rows.Next() {
+		err := rows.Scan(&id, &name, &savedKey)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		key := authorname.Key(name)
+		if key == "" {
+			continue
+		}
+
+		groups[key] = append(groups[key], id)
+		names[id] = name
+		savedKeys[id] = savedKey.String
+	}
+
+	err = rows.Close()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	mergedAuthors := 0
+	movedLinks := 0
+	for _, ids := range groups {
+		for _, id := range ids[1:] {
+			// links that the kept author already has are deleted so that the primary key is never violated
+			_, err := tx.ExecContext(ctx, rebind(`DELETE FROM author_book WHERE author_id = ? AND book_id IN (
+				SELECT book_id FROM (SELECT book_id FROM author_book WHERE author_id = ?) kept_links
+			)`), id, ids[0])
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			result, err := tx.ExecContext(ctx, rebind(`UPDATE author_book SET author_id = ? WHERE author_id = ?`), ids[0], id)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			affectedRows, err := result.RowsAffected()
+			if err != nil {
+				log.Fatal(err)
+			}
+			movedLinks += int(affectedRows)
+
+			_, err = tx.ExecContext(ctx, rebind(`UPDATE author_aliases SET author_id = ? WHERE author_id = ?`), ids[0], id)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			_, err = tx.ExecContext(ctx, rebind(`DELETE FROM authors WHERE id = ?`), id)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			mergedAuthors++
+		}
+	}
+
+	// the kept authors are renamed after all the merged ones are deleted so that the unique keys are never violated
+	var aliasRows [][]any
+	for key, ids := range groups {
+		keptId := ids[0]
+		normalizedName := authorname.Normalize(names[keptId])
+		if normalizedName != names[keptId] || key != savedKeys[keptId] {
+			_, err := tx.ExecContext(ctx, rebind(`UPDATE authors SET name = ?, name_key = ? WHERE id = ?`), normalizedName, key, keptId)
+			if err != nil {
+				log.Fatal(err)
+			}
+		}
+
+		for _, id := range ids {
+			aliasRows = append(aliasRows, []any{names[id], keptId})
+		}
+	}
+
+	// sorted for the same reason as the lookup names
+	slices.SortFunc(aliasRows, func(a []any, b []any) int {
+		return strings.Compare(a[0].(string), b[0].(string))
+	})
+
+	insertRows(ctx, tx, `INSERT INTO author_aliases (alias, author_id) VALUES `, aliasRows, onConflictIgnore("alias"))
+
+	err = tx.Commit()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	return mergedAuthors, movedLinks
+}
+
+// normalizeAuthors is the migration that normalizes the authors that were saved before they were normalized
+func normalizeAuthors(ctx context.Context, db *sql.DB) {
+	mergedAuthors, movedLinks := NormalizeAuthors(ctx, db)
+	fmt.Printf("Merged %v authors and moved %v book links to the normalized authors\n", mergedAuthors, movedLinks)
+}
@@ -0,0 +1,526 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+
+	"github.com/zaelmyth/book-data-collector/internal/isbn"
+)
+
+// identifierColumns are the books columns that identify a book, books that share any of them are duplicates
+var identifierColumns = []string{"isbn13", "google_id", "open_library_id"}
+
+// bookTables are the tables that reference books by a book_id column
+var bookTables = []string{
+	"author_book",
+	"book_subject",
+	"industry_identifiers",
+	"open_library_ratings",
+	"open_library_reading_logs",
+	"book_changes",
+	"book_prices",
+	"other_isbns",
+	"reviews",
+	"book_dimensions",
+	"book_images",
+	"book_series",
+	"book_access",
+}
+
+// singleRowBookTables are the tables of bookTables that have one row per book
+var singleRowBookTables = []string{"book_dimensions", "book_access"}
+
+// rowTable is a table of bookTables with an id column, rows with the same values in the columns are duplicates
+type rowTable struct {
+	tableName string
+	columns   []string
+}
+
+// rowTables are the tables whose duplicate rows are removed after the books are merged
+var rowTables = []rowTable{
+	{"industry_identifiers", []string{"type", "identifier"}},
+	{"other_isbns", []string{"isbn", "binding"}},
+	{"reviews", []string{"review"}},
+	{"book_images", []string{"size", "url"}},
+	{"book_series", []string{"series_id", "series_book_type", "order_number"}},
+}
+
+// dedupeSchema is the schema that merging books uses, the migrations use the one of their version
+type dedupeSchema struct {
+	identifierColumns   []string
+	bookColumns         []string
+	bookTables          []string
+	singleRowBookTables []string
+	// hasPublishedDates is true if the books have the parsed published date columns
+	hasPublishedDates bool
+	// normalizesIsbns is true if books are grouped by their normalized isbn-13 and their isbns are normalized
+	normalizesIsbns bool
+}
+
+// dedupeSchemaV8 is the schema of the dedupe_books migration, the books only had the original tables
+var dedupeSchemaV8 = dedupeSchema{
+	identifierColumns: []string{"isbn13", "google_id", "open_library_id"},
+	bookColumns: []string{
+		"google_id", "title", "title_long", "subtitle", "isbn", "isbn13", "dewey_decimal", "binding", "publisher_id",
+		"language_id", "date_published", "edition", "pages", "dimensions", "overview", "image", "msrp", "excerpt",
+		"synopsis", "related_type", "average_rating", "rating_count", "main_category", "open_library_id",
+	},
+	bookTables: []string{
+		"author_book", "book_subject", "industry_identifiers", "open_library_ratings", "open_library_reading_logs",
+		"book_changes",
+	},
+}
+
+// dedupeSchemaV19 is the schema of the normalize_book_isbns migration, before the published date columns were added
+var dedupeSchemaV19 = dedupeSchema{
+	identifierColumns: dedupeSchemaV8.identifierColumns,
+	bookColumns:       dedupeSchemaV8.bookColumns,
+	bookTables: []string{
+		"author_book", "book_subject", "industry_identifiers", "open_library_ratings", "open_library_reading_logs",
+		"book_changes", "book_prices", "other_isbns", "reviews", "book_dimensions", "book_images", "book_series",
+		"book_access",
+	},
+	singleRowBookTables: []string{"book_dimensions", "book_access"},
+	normalizesIsbns:     true,
+}
+
+// currentDedupeSchema is the schema after all the migrations, which the dedupe utility runs on
+var currentDedupeSchema = dedupeSchema{
+	identifierColumns:   identifierColumns,
+	bookColumns:         bookColumns,
+	bookTables:          bookTables,
+	singleRowBookTables: singleRowBookTables,
+	hasPublishedDates:   true,
+	normalizesIsbns:     true,
+}
+
+// DedupeBooks returns how many books and link rows were removed and how many books had their isbns normalized
+func DedupeBooks(ctx context.Context, db *sql.DB) (int, int, int) {
+	return dedupeBooksWithSchema(ctx, db, currentDedupeSchema)
+}
+
+// dedupeBooks is the migration that merges the duplicates before the unique keys are created
+func dedupeBooks(ctx context.Context, db *sql.DB) {
+	mergedBooks, removedRows, _ := dedupeBooksWithSchema(ctx, db, dedupeSchemaV8)
+	fmt.Printf("Merged %v duplicate books and removed %v duplicate or orphaned link rows\n", mergedBooks, removedRows)
+}
+
+// dedupeBookIsbns is the migration that normalizes the isbns and merges the books that have the same normalized isbn
+func dedupeBookIsbns(ctx context.Context, db *sql.DB) {
+	mergedBooks, removedRows, normalizedBooks := dedupeBooksWithSchema(ctx, db, dedupeSchemaV19)
+	fmt.Printf("Merged %v duplicate books, removed %v duplicate or orphaned link rows and normalized the isbns of %v books\n", mergedBooks, removedRows, normalizedBooks)
+}
+
+func dedupeBooksWithSchema(ctx context.Context, db *sql.DB, schema dedupeSchema) (int, int, int) {
+	for _, column := range schema.identifierColumns {
+		_, err := db.ExecContext(ctx, `UPDATE books SET `+column+` = NULL WHERE `+column+` = ''`)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	duplicates := getDuplicateBooks(ctx, db, schema)
+	for bookId, duplicateIds := range duplicates {
+		mergeBooks(ctx, db, schema, bookId, duplicateIds)
+	}
+
+	removedRows := removeDuplicateLinks(ctx, db, "author_book", "author_id")
+	removedRows += removeDuplicateLinks(ctx, db, "book_subject", "subject_id")
+	for _, table := range rowTables {
+		if slices.Contains(schema.bookTables, table.tableName) {
+			removedRows += removeDuplicateRows(ctx, db, table.tableName, table.columns)
+		}
+	}
+	removedRows += removeOrphanedRows(ctx, db)
+
+	// the isbns are normalized after the merge because books that share the normalized isbn-13 would violate its
+	// unique key
+	normalizedBooks := 0
+	if schema.normalizesIsbns {
+		normalizedBooks = normalizeBookIsbns(ctx, db)
+	}
+
+	mergedBooks := 0
+	for _, duplicateIds := range duplicates {
+		mergedBooks += len(duplicateIds)
+	}
+
+	return mergedBooks, removedRows, normalizedBooks
+}
+
+// getDuplicateBooks returns the duplicate ids by the id of the kept book, which is the lowest across all identifiers
+func getDuplicateBooks(ctx context.Context, db *sql.DB, schema dedupeSchema) map[int][]int {
+	// the kept id of a book is found by following the kept ids until the book that is kept
+	keptIds := make(map[int]int)
+	getKeptId := func(id int) int {
+		for {
+			keptId, isDuplicate := keptIds[id]
+			if !isDuplicate {
+				return id
+			}
+			id = keptId
+		}
+	}
+
+	for _, column := range schema.identifierColumns {
+		var groups map[string][]int
+		if column == "isbn13" && schema.normalizesIsbns {
+			groups = getIsbnGroups(ctx, db)
+		} else {
+			groups = getIdentifierGroups(ctx, db, column)
+		}
+
+		for _, ids := range groups {
+			for _, id := range ids[1:] {
+				keptId, duplicateKeptId := getKeptId(ids[0]), getKeptId(id)
+				if keptId == duplicateKeptId {
+					continue
+				}
+
+				keptIds[max(keptId, duplicateKeptId)] = min(keptId, duplicateKeptId)
+			}
+		}
+	}
+
+	duplicates := make(map[int][]int)
+	for id := range keptIds {
+		keptId := getKeptId(id)
+		duplicates[keptId] = append(duplicates[keptId], id)
+	}
+
+	for _, duplicateIds := range duplicates {
+		slices.Sort(duplicateIds)
+	}
+
+	return duplicates
+}
+
+// getIdentifierGroups returns the ids of the books that share a value of the column by value, ordered by id
+func getIdentifierGroups(ctx context.Context, db *sql.DB, column string) map[string][]int {
+	rows, err := db.QueryContext(ctx, `SELECT id, `+column+` FROM books WHERE `+column+` IN (
+		SELECT `+column+` FROM books WHERE `+column+` IS NOT NULL GROUP BY `+column+` HAVING COUNT(*) > 1
+	) ORDER BY id`)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			log.Fatal(err)
+		}
+	}(rows)
+
+	groups := make(map[string][]int)
+	var id int
+	var value string
+	for rows.Next() {
+		err := rows.Scan(&id, &value)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		groups[value] = append(groups[value], id)
+	}
+
+	return groups
+}
+
+// getIsbnGroups groups by the normalized isbn-13, or the saved isbn13 of books without a valid isbn
+func getIsbnGroups(ctx context.Context, db *sql.DB) map[string][]int {
+	rows, err := db.QueryContext(ctx, `SELECT id, isbn13, isbn FROM books WHERE isbn13 IS NOT NULL OR isbn IS NOT NULL ORDER BY id`)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			log.Fatal(err)
+		}
+	}(rows)
+
+	groups := make(map[string][]int)
+	var id int
+	var isbn13, isbn10 sql.NullString
+	for rows.Next() {
+		err := rows.Scan(&id, &isbn13, &isbn10)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		key, _ := isbn.Normalize(isbn13.String, isbn10.String)
+		if key == "" {
+			key = isbn13.String
+		}
+
+		if key != "" {
+			groups[key] = append(groups[key], id)
+		}
+	}
+
+	for key, ids := range groups {
+		if len(ids) < 2 {
+			delete(groups, key)
+		}
+	}
+
+	return groups
+}
+
+// normalizeBookIsbns leaves the isbns that are not valid as they are and returns how many books were changed
+func normalizeBookIsbns(ctx context.Context, db *sql.DB) int {
+	rows, err := db.QueryContext(ctx, `SELECT id, isbn13, isbn FROM books WHERE isbn13 IS NOT NULL OR isbn IS NOT NULL`)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var changedBooks [][]any
+	var id int
+	var isbn13, isbn10 sql.NullString
+	for rows.Next() {
+		err := rows.Scan(&id, &isbn13, &isbn10)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		normalizedIsbn13, normalizedIsbn10 := isbn.Normalize(isbn13.String, isbn10.String)
+		if normalizedIsbn13 == "" || (normalizedIsbn13 == isbn13.String && normalizedIsbn10 == isbn10.String) {
+			continue
+		}
+
+		changedBooks = append(changedBooks, []any{normalizedIsbn13, nullIfEmpty(normalizedIsbn10), id})
+	}
+
+	err = rows.Close()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	for _, changedBook := range changedBooks {
+		_, err := tx.ExecContext(ctx, rebind(`UPDATE books SET isbn13 = ?, isbn = ? WHERE id = ?`), changedBook...)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	return len(changedBooks)
+}
+
+// mergeBooks fills the empty columns of the book from its duplicates and moves their rows to it
+func mergeBooks(ctx context.Context, db *sql.DB, schema dedupeSchema, bookId int, duplicateIds []int) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// the kept book is selected first so that its values take precedence
+	args := []any{bookId}
+	for _, duplicateId := range duplicateIds {
+		args = append(args, duplicateId)
+	}
+
+	mergedValues := make([]sql.NullString, len(schema.bookColumns))
+	values := make([]sql.NullString, len(schema.bookColumns))
+	destinations := make([]any, 0, len(schema.bookColumns))
+	for i := range values {
+		destinations = append(destinations, &values[i])
+	}
+
+	rowsCount := 0
+	queryIn(ctx, tx, `SELECT `+strings.Join(schema.bookColumns, ", ")+` FROM books WHERE id IN (%s) ORDER BY CASE WHEN id = `+fmt.Sprint(bookId)+` THEN 0 ELSE 1 END, id`, args, func(rows *sql.Rows) {
+		err := rows.Scan(destinations...)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		for i, value := range values {
+			if rowsCount == 0 || (isEmptyValue(mergedValues[i]) && !isEmptyValue(value)) {
+				mergedValues[i] = value
+			}
+		}
+		rowsCount++
+	})
+
+	var assignments []string
+	var updateArgs []any
+	for i, column := range schema.bookColumns {
+		assignments = append(assignments, column+` = ?`)
+		if mergedValues[i].Valid {
+			updateArgs = append(updateArgs, mergedValues[i].String)
+		} else {
+			updateArgs = append(updateArgs, nil)
+		}
+	}
+
+	// the parsed date follows date_published, which can come from a duplicate
+	if schema.hasPublishedDates {
+		for _, column := range publishedDateColumns {
+			assignments = append(assignments, column+` = ?`)
+		}
+		updateArgs = append(updateArgs, getPublishedDateValues(mergedValues[slices.Index(schema.bookColumns, "date_published")].String)...)
+	}
+
+	for _, duplicateId := range duplicateIds {
+		for _, tableName := range []string{"author_book", "book_subject"} {
+			column := "author_id"
+			if tableName == "book_subject" {
+				column = "subject_id"
+			}
+
+			// links that the kept book already has are deleted so that the unique keys are never violated
+			_, err := tx.ExecContext(ctx, rebind(`DELETE FROM `+tableName+` WHERE book_id = ? AND `+column+` IN (
+				SELECT `+column+` FROM (SELECT `+column+` FROM `+tableName+` WHERE book_id = ?) kept_links
+			)`), duplicateId, bookId)
+			if err != nil {
+				log.Fatal(err)
+			}
+		}
+
+		// the row of a duplicate is only kept if the book doesn't have one
+		for _, tableName := range schema.singleRowBookTables {
+			_, err := tx.ExecContext(ctx, rebind(`DELETE FROM `+tableName+` WHERE book_id = ? AND EXISTS (
+				SELECT book_id FROM (SELECT book_id FROM `+tableName+` WHERE book_id = ?) kept_rows
+			)`), duplicateId, bookId)
+			if err != nil {
+				log.Fatal(err)
+			}
+		}
+
+		for _, tableName := range schema.bookTables {
+			_, err := tx.ExecContext(ctx, rebind(`UPDATE `+tableName+` SET book_id = ? WHERE book_id = ?`), bookId, duplicateId)
+			if err != nil {
+				log.Fatal(err)
+			}
+		}
+
+		_, err = tx.ExecContext(ctx, rebind(`DELETE FROM books WHERE id = ?`), duplicateId)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	// identifiers are unique so they are moved to the kept book after the duplicates are deleted
+	_, err = tx.ExecContext(ctx, rebind(`UPDATE books SET `+strings.Join(assignments, ", ")+` WHERE id = ?`), append(updateArgs, bookId)...)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// removeDuplicateLinks deletes all the rows of a duplicated link and inserts one again, link tables don't have an id
+func removeDuplicateLinks(ctx context.Context, db *sql.DB, tableName string, column string) int {
+	rows, err := db.QueryContext(ctx, `SELECT book_id, `+column+`, COUNT(*) FROM `+tableName+` GROUP BY book_id, `+column+` HAVING COUNT(*) > 1`)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var duplicateLinks [][]any
+	removedRows := 0
+	var bookId, linkedId sql.NullInt64
+	var count int
+	for rows.Next() {
+		err := rows.Scan(&bookId, &linkedId, &count)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		duplicateLinks = append(duplicateLinks, []any{bookId, linkedId})
+		removedRows += count - 1
+	}
+
+	err = rows.Close()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if len(duplicateLinks) == 0 {
+		return 0
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	for _, link := range duplicateLinks {
+		_, err := tx.ExecContext(ctx, rebind(`DELETE FROM `+tableName+` WHERE book_id = ? AND `+column+` = ?`), link...)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	insertRows(ctx, tx, `INSERT INTO `+tableName+` (book_id, `+column+`) VALUES `, duplicateLinks, ``)
+
+	err = tx.Commit()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	return removedRows
+}
+
+// removeDuplicateRows keeps the first of the rows of a book that have the same values in the columns
+func removeDuplicateRows(ctx context.Context, db *sql.DB, tableName string, columns []string) int {
+	// the ids to keep are selected from a derived table because mysql doesn't allow selecting from the table that rows
+	// are deleted from
+	result, err := db.ExecContext(ctx, `DELETE FROM `+tableName+` WHERE id NOT IN (
+		SELECT id FROM (SELECT MIN(id) AS id FROM `+tableName+` GROUP BY book_id, `+strings.Join(columns, ", ")+`) kept_rows
+	)`)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	return getAffectedRows(result)
+}
+
+// removeOrphanedRows removes the links without ids too because the columns of primary keys can't be null
+func removeOrphanedRows(ctx context.Context, db *sql.DB) int {
+	queries := []string{
+		`DELETE FROM author_book WHERE book_id IS NULL OR author_id IS NULL OR book_id NOT IN (SELECT id FROM books) OR author_id NOT IN (SELECT id FROM authors)`,
+		`DELETE FROM book_subject WHERE book_id IS NULL OR subject_id IS NULL OR book_id NOT IN (SELECT id FROM books) OR subject_id NOT IN (SELECT id FROM subjects)`,
+		`DELETE FROM industry_identifiers WHERE book_id NOT IN (SELECT id FROM books)`,
+	}
+
+	removedRows := 0
+	for _, query := range queries {
+		result, err := db.ExecContext(ctx, query)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		removedRows += getAffectedRows(result)
+	}
+
+	return removedRows
+}
+
+func getAffectedRows(result sql.Result) int {
+	affectedRows, err := result.RowsAffected()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	return int(affectedRows)
+}
+
+// isEmptyValue is true for the null, empty and zero values of missing api response fields
+func isEmptyValue(value sql.NullString) bool {
+	return !value.Valid || value.String == "" || value.String == "0"
+}
@@ -0,0 +1,138 @@
+package db
+
+import (
+	"database/sql"
+	"log"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/zaelmyth/book-data-collector/internal/configuration"
+)
+
+// driver is set when a database is opened so that queries can be written for it without passing the configuration
+// around, all databases of a run use the same driver
+var driver = "mysql"
+
+// sqliteParameters make concurrent writes wait for each other instead of failing and make transactions take the write
+// lock right away so that two transactions can't deadlock while upgrading their locks, times are saved in a format that
+// sorts the same way as the time
+const sqliteParameters = "?_pragma=busy_timeout(60000)&_pragma=journal_mode(WAL)&_pragma=foreign_keys(1)&_txlock=immediate&_time_format=sqlite"
+
+func openDatabase(config configuration.Config, name string) *sql.DB {
+	driver = config.DbDriver
+
+	if driver == "sqlite" {
+		// books and progress are saved in the same file
+		db, err := sql.Open("sqlite", "file:"+url.PathEscape(config.DbFile)+sqliteParameters)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		// sqlite allows only one writer at a time so more connections would only wait for the lock
+		db.SetMaxOpenConns(1)
+
+		return db
+	}
+
+	if driver == "postgres" {
+		// the books and progress databases are schemas of the same postgres database
+		db, err := sql.Open("postgres", getPostgresConnectionString(config, name))
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		return db
+	}
+
+	mysqlConnectionString := getMysqlConnectionString(config)
+	// the database has to be declared in the connection instead of with a "USE" statement because of concurrency issues
+	db, err := sql.Open("mysql", mysqlConnectionString+name+"?charset=utf8mb4&parseTime=true")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	return db
+}
+
+// getPostgresConnectionString sets the schema as the search path so that queries don't have to prefix table names with
+// it. The ssl mode and other connection settings can be set with the standard PG environment variables.
+func getPostgresConnectionString(config configuration.Config, schema string) string {
+	connectionUrl := url.URL{
+		Scheme: "postgres",
+		User:   url.UserPassword(config.DbUsername, config.DbPassword),
+		Host:   net.JoinHostPort(config.DbHost, config.DbPort),
+		Path:   config.DbName,
+	}
+
+	if schema != "" {
+		connectionUrl.RawQuery = url.Values{"search_path": {schema}}.Encode()
+	}
+
+	return connectionUrl.String()
+}
+
+func primaryKey() string {
+	switch driver {
+	case "sqlite":
+		return "INTEGER PRIMARY KEY AUTOINCREMENT"
+	case "postgres":
+		return "SERIAL PRIMARY KEY"
+	default:
+		return "INTEGER PRIMARY KEY AUTO_INCREMENT"
+	}
+}
+
+// timestamp is the column type for a date with a time, postgres doesn't have DATETIME and mysql TIMESTAMP can't store
+// dates after 2038
+func timestamp() string {
+	if driver == "postgres" {
+		return "TIMESTAMP"
+	}
+
+	return "DATETIME"
+}
+
+// rebind replaces the ? placeholders that mysql and sqlite use with the numbered placeholders that postgres uses
+func rebind(query string) string {
+	if driver != "postgres" {
+		return query
+	}
+
+	var builder strings.Builder
+	placeholder := 0
+	for _, char := range query {
+		if char != '?' {
+			builder.WriteRune(char)
+			continue
+		}
+
+		placeholder++
+		builder.WriteString("$" + strconv.Itoa(placeholder))
+	}
+
+	return builder.String()
+}
+
+// onConflictIgnore is the suffix of an insert that skips the rows that violate a unique key, mysql has no syntax to
+// ignore only those rows so a column is updated to its own value instead
+func onConflictIgnore(column string) string {
+	if driver == "mysql" {
+		return ` ON DUPLICATE KEY UPDATE ` + column + ` = ` + column
+	}
+
+	return ` ON CONFLICT DO NOTHING`
+}
+
+// blob is the column type for binary data
+func blob() string {
+	switch driver {
+	case "postgres":
+		return "BYTEA"
+	case "mysql":
+		return "LONGBLOB"
+	default:
+		return "BLOB"
+	}
+}
@@ -0,0 +1,88 @@
+package db
+
+import (
+	"context"
+	"testing"
+)
+
+// setDriver changes the driver for the test and restores it after
+func setDriver(t *testing.T, driverName string) {
+	previousDriver := driver
+	driver = driverName
+	t.Cleanup(func() {
+		driver = previousDriver
+	})
+}
+
+func TestRebind(t *testing.T) {
+	tests := []struct {
+		driver string
+		query  string
+		want   string
+	}{
+		{"mysql", `SELECT id FROM books WHERE isbn13 = ? AND isbn = ?`, `SELECT id FROM books WHERE isbn13 = ? AND isbn = ?`},
+		{"sqlite", `SELECT id FROM books WHERE isbn13 = ? AND isbn = ?`, `SELECT id FROM books WHERE isbn13 = ? AND isbn = ?`},
+		{"postgres", `SELECT id FROM books WHERE isbn13 = ? AND isbn = ?`, `SELECT id FROM books WHERE isbn13 = $1 AND isbn = $2`},
+		{"postgres", `INSERT INTO authors (name_key, name) VALUES (?, ?), (?, ?)`, `INSERT INTO authors (name_key, name) VALUES ($1, $2), ($3, $4)`},
+		{"postgres", `SELECT id FROM books`, `SELECT id FROM books`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.driver, func(t *testing.T) {
+			setDriver(t, test.driver)
+
+			got := rebind(test.query)
+			if got != test.want {
+				t.Errorf("rebind(%q) = %q, want %q", test.query, got, test.want)
+			}
+		})
+	}
+}
+
+func TestOnConflictIgnore(t *testing.T) {
+	tests := []struct {
+		driver string
+		want   string
+	}{
+		{"mysql", ` ON DUPLICATE KEY UPDATE name = name`},
+		{"sqlite", ` ON CONFLICT DO NOTHING`},
+		{"postgres", ` ON CONFLICT DO NOTHING`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.driver, func(t *testing.T) {
+			setDriver(t, test.driver)
+
+			got := onConflictIgnore("name")
+			if got != test.want {
+				t.Errorf("onConflictIgnore(%q) = %q, want %q", "name", got, test.want)
+			}
+		})
+	}
+}
+
+func TestOnConflictIgnoreSkipsDuplicates(t *testing.T) {
+	forEachDriver(t, func(t *testing.T, databases testDatabases) {
+		ctx := context.Background()
+		MigrateUp(ctx, databases.booksDb, databases.progressDb)
+
+		tx, err := databases.booksDb.BeginTx(ctx, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rows := [][]any{{"Fiction"}, {"History"}}
+		insertRows(ctx, tx, `INSERT INTO subjects (name) VALUES `, rows, onConflictIgnore("name"))
+		insertRows(ctx, tx, `INSERT INTO subjects (name) VALUES `, append(rows, []any{"Poetry"}), onConflictIgnore("name"))
+
+		err = tx.Commit()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		count := countRows(t, databases.booksDb, `subjects`)
+		if count != 3 {
+			t.Errorf("saved %v subjects, want 3", count)
+		}
+	})
+}
@@ -0,0 +1,129 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"slices"
+)
+
+// NormalizeLanguages returns how many languages were renamed or merged and how many books were moved
+func NormalizeLanguages(ctx context.Context, db *sql.DB) (int, int) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	rows, err := tx.QueryContext(ctx, `SELECT id, name FROM languages ORDER BY id`)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	savedIds := make(map[string]int)
+	groups := make(map[string][]int)
+	names := make(map[int]string)
+	var id int
+	var name string
+	for rows.Next() {
+		err := rows.Scan(&id, &name)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		savedIds[name] = id
+		names[id] = name
+		if name == "" || languageName(name) != name {
+			groups[languageName(name)] = append(groups[languageName(name)], id)
+		}
+	}
+
+	err = rows.Close()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	normalizedLanguages := 0
+	movedBooks := 0
+	for code, ids := range groups {
+		// the language that is named by the code is kept, or the first one of the group is renamed to it. The books of
+		// the empty language are left without a language.
+		keptId, isSaved := savedIds[code]
+		if code == "" {
+			keptId = 0
+		} else if !isSaved {
+			keptId = ids[0]
+		}
+
+		for _, id := range ids {
+			if id == keptId {
+				continue
+			}
+
+			result, err := tx.ExecContext(ctx, rebind(`UPDATE books SET language_id = ? WHERE language_id = ?`), nullIfZeroInt(keptId), id)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			affectedRows, err := result.RowsAffected()
+			if err != nil {
+				log.Fatal(err)
+			}
+			movedBooks += int(affectedRows)
+
+			if keptId != 0 {
+				_, err = tx.ExecContext(ctx, rebind(`UPDATE language_aliases SET language_id = ? WHERE language_id = ?`), keptId, id)
+				if err != nil {
+					log.Fatal(err)
+				}
+			}
+
+			_, err = tx.ExecContext(ctx, rebind(`DELETE FROM languages WHERE id = ?`), id)
+			if err != nil {
+				log.Fatal(err)
+			}
+		}
+
+		if code == "" {
+			normalizedLanguages += len(ids)
+			continue
+		}
+
+		if !isSaved {
+			_, err := tx.ExecContext(ctx, rebind(`UPDATE languages SET name = ? WHERE id = ?`), code, keptId)
+			if err != nil {
+				log.Fatal(err)
+			}
+		}
+
+		aliases := make([]string, 0, len(ids))
+		for _, id := range ids {
+			aliases = append(aliases, names[id])
+		}
+
+		// sorted for the same reason as the lookup names
+		slices.Sort(aliases)
+
+		aliasRows := make([][]any, 0, len(aliases))
+		for _, alias := range aliases {
+			aliasRows = append(aliasRows, []any{alias, keptId})
+		}
+
+		insertRows(ctx, tx, `INSERT INTO language_aliases (alias, language_id) VALUES `, aliasRows, onConflictIgnore("alias"))
+
+		normalizedLanguages += len(ids)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	return normalizedLanguages, movedBooks
+}
+
+// normalizeLanguages is the migration that normalizes the languages that were saved before they were normalized
+func normalizeLanguages(ctx context.Context, db *sql.DB) {
+	normalizedLanguages, movedBooks := NormalizeLanguages(ctx, db)
+	fmt.Printf("Normalized %v languages and moved %v books to the normalized languages\n", normalizedLanguages, movedBooks)
+}
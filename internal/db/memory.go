@@ -3,92 +3,142 @@ package db
 import (
 	"context"
 	"database/sql"
+	"log"
 	"sync"
 )
 
 type SavedData struct {
-	Books           map[string]struct{}
-	BooksMutex      *sync.RWMutex
-	Authors         map[string]int
-	AuthorsMutex    *sync.Mutex
-	Subjects        map[string]int
-	SubjectsMutex   *sync.Mutex
-	Publishers      map[string]int
-	PublishersMutex *sync.Mutex
-	Languages       map[string]int
-	LanguagesMutex  *sync.Mutex
-	Queries         map[string]struct{}
-	QueriesMutex    *sync.RWMutex
+	Books              map[string]struct{}
+	SavingBooks        map[string]struct{}
+	BooksMutex         *sync.RWMutex
+	Authors            map[string]int
+	AuthorsMutex       *sync.Mutex
+	AuthorAliases      map[string]struct{}
+	AuthorAliasesMutex *sync.Mutex
+	Subjects           map[string]int
+	SubjectsMutex      *sync.Mutex
+	Publishers         map[string]int
+	PublishersMutex    *sync.Mutex
+	Languages          map[string]int
+	LanguagesMutex     *sync.Mutex
+	Queries            map[string]struct{}
+	QueriesMutex       *sync.RWMutex
+	QueryPages         map[string]QueryProgress
+	QueryPagesMutex    *sync.RWMutex
+	Isbns              map[string]struct{}
+	IsbnsMutex         *sync.RWMutex
 }
 
-func (savedData *SavedData) IsBookSaved(id string) bool {
-	savedData.BooksMutex.RLock()
-	defer savedData.BooksMutex.RUnlock()
+// QueryProgress is how far a query with multiple pages got before it was interrupted
+type QueryProgress struct {
+	Total    int
+	LastPage int
+}
+
+// lookupIds are the ids of lookup table rows by table name and row name
+type lookupIds map[string]map[string]int
+
+// ReserveBook reserves the book until it is added to memory after it is committed, so that it is not saved by another
+// goroutine at the same time. Saved books are only reserved if they are refreshed. The second value is true if the
+// book is saved.
+func (savedData *SavedData) ReserveBook(id string, isRefresh bool) (bool, bool) {
+	savedData.BooksMutex.Lock()
+	defer savedData.BooksMutex.Unlock()
 
 	_, isSaved := savedData.Books[id]
+	_, isSaving := savedData.SavingBooks[id]
+	if isSaving || (isSaved && !isRefresh) {
+		return false, isSaved
+	}
 
-	return isSaved
+	savedData.SavingBooks[id] = struct{}{}
+
+	return true, isSaved
 }
 
-func (savedData *SavedData) AddBookToMemory(id string) bool {
+// AddBooksToMemory is called after the transaction that saved the books is committed so that other books never use the
+// id of a lookup row that was rolled back
+func (savedData *SavedData) AddBooksToMemory(bookIds []string, newIds lookupIds) {
+	for tableName, ids := range newIds {
+		savedIds, mutex := savedData.getLookup(tableName)
+
+		mutex.Lock()
+		for name, id := range ids {
+			savedIds[name] = id
+		}
+		mutex.Unlock()
+	}
+
 	savedData.BooksMutex.Lock()
 	defer savedData.BooksMutex.Unlock()
 
-	savedData.Books[id] = struct{}{}
-
-	return true
+	for _, id := range bookIds {
+		delete(savedData.SavingBooks, id)
+		savedData.Books[id] = struct{}{}
+	}
 }
 
-func (savedData *SavedData) SaveAuthor(ctx context.Context, db *sql.DB, name string) int {
-	savedData.AuthorsMutex.Lock()
-	defer savedData.AuthorsMutex.Unlock()
-
-	id, isSaved := savedData.Authors[name]
-	if !isSaved {
-		id = insertData(ctx, db, "authors", name)
-		savedData.Authors[name] = id
+// getMissingAuthorAliases returns the aliases that are not saved
+func (savedData *SavedData) getMissingAuthorAliases(aliases []string) []string {
+	savedData.AuthorAliasesMutex.Lock()
+	defer savedData.AuthorAliasesMutex.Unlock()
+
+	var missingAliases []string
+	for _, alias := range aliases {
+		_, isSaved := savedData.AuthorAliases[alias]
+		if !isSaved {
+			missingAliases = append(missingAliases, alias)
+		}
 	}
 
-	return id
+	return missingAliases
 }
 
-func (savedData *SavedData) SaveSubject(ctx context.Context, db *sql.DB, name string) int {
-	savedData.SubjectsMutex.Lock()
-	defer savedData.SubjectsMutex.Unlock()
+// addAuthorAliasesToMemory is called after the transaction that saved the aliases is committed
+func (savedData *SavedData) addAuthorAliasesToMemory(aliases []string) {
+	savedData.AuthorAliasesMutex.Lock()
+	defer savedData.AuthorAliasesMutex.Unlock()
 
-	id, isSaved := savedData.Subjects[name]
-	if !isSaved {
-		id = insertData(ctx, db, "subjects", name)
-		savedData.Subjects[name] = id
+	for _, alias := range aliases {
+		savedData.AuthorAliases[alias] = struct{}{}
 	}
-
-	return id
 }
 
-func (savedData *SavedData) SavePublisher(ctx context.Context, db *sql.DB, name string) int {
-	savedData.PublishersMutex.Lock()
-	defer savedData.PublishersMutex.Unlock()
-
-	id, isSaved := savedData.Publishers[name]
-	if !isSaved {
-		id = insertData(ctx, db, "publishers", name)
-		savedData.Publishers[name] = id
+// getLookupIds returns the ids of the names that are saved and the names that are not
+func (savedData *SavedData) getLookupIds(tableName string, names []string) (map[string]int, []string) {
+	savedIds, mutex := savedData.getLookup(tableName)
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	ids := make(map[string]int)
+	var missingNames []string
+	for _, name := range names {
+		id, isSaved := savedIds[name]
+		if isSaved {
+			ids[name] = id
+		} else {
+			missingNames = append(missingNames, name)
+		}
 	}
 
-	return id
+	return ids, missingNames
 }
 
-func (savedData *SavedData) SaveLanguage(ctx context.Context, db *sql.DB, name string) int {
-	savedData.LanguagesMutex.Lock()
-	defer savedData.LanguagesMutex.Unlock()
-
-	id, isSaved := savedData.Languages[name]
-	if !isSaved {
-		id = insertData(ctx, db, "languages", name)
-		savedData.Languages[name] = id
+func (savedData *SavedData) getLookup(tableName string) (map[string]int, *sync.Mutex) {
+	switch tableName {
+	case "publishers":
+		return savedData.Publishers, savedData.PublishersMutex
+	case "languages":
+		return savedData.Languages, savedData.LanguagesMutex
+	case "authors":
+		return savedData.Authors, savedData.AuthorsMutex
+	case "subjects":
+		return savedData.Subjects, savedData.SubjectsMutex
+	default:
+		log.Fatal("Invalid table name")
+		return nil, nil
 	}
-
-	return id
 }
 
 func (savedData *SavedData) IsQuerySaved(query string) bool {
@@ -109,4 +159,66 @@ func (savedData *SavedData) SaveQuery(ctx context.Context, db *sql.DB, query str
 		insertQuery(ctx, db, query)
 		savedData.Queries[query] = struct{}{}
 	}
+
+	savedData.QueryPagesMutex.Lock()
+	defer savedData.QueryPagesMutex.Unlock()
+
+	_, hasProgress := savedData.QueryPages[query]
+	if hasProgress {
+		deleteQueryProgress(ctx, db, query)
+		delete(savedData.QueryPages, query)
+	}
+}
+
+func (savedData *SavedData) GetQueryProgress(query string) (QueryProgress, bool) {
+	savedData.QueryPagesMutex.RLock()
+	defer savedData.QueryPagesMutex.RUnlock()
+
+	progress, hasProgress := savedData.QueryPages[query]
+
+	return progress, hasProgress
+}
+
+// SaveQueryPage only moves the progress forward because pages of the same query can be saved out of order by different
+// goroutines
+func (savedData *SavedData) SaveQueryPage(ctx context.Context, db *sql.DB, query string, total int, page int) {
+	savedData.QueryPagesMutex.Lock()
+	defer savedData.QueryPagesMutex.Unlock()
+
+	progress, hasProgress := savedData.QueryPages[query]
+	if hasProgress && progress.LastPage >= page {
+		return
+	}
+
+	progress = QueryProgress{Total: total, LastPage: page}
+	saveQueryProgress(ctx, db, query, progress)
+	savedData.QueryPages[query] = progress
+}
+
+// IsIsbnSearched is true for isbns that don't need to be searched again
+func (savedData *SavedData) IsIsbnSearched(isbn string) bool {
+	savedData.IsbnsMutex.RLock()
+	defer savedData.IsbnsMutex.RUnlock()
+
+	_, isSearched := savedData.Isbns[isbn]
+
+	return isSearched
+}
+
+func (savedData *SavedData) SaveIsbns(ctx context.Context, db *sql.DB, isbns []string, status string) {
+	if len(isbns) == 0 {
+		return
+	}
+
+	savedData.IsbnsMutex.Lock()
+	defer savedData.IsbnsMutex.Unlock()
+
+	saveSearchedIsbns(ctx, db, isbns, status)
+
+	// isbns with errors are kept out of memory so they are searched again on the next run
+	if status != IsbnError {
+		for _, isbn := range isbns {
+			savedData.Isbns[isbn] = struct{}{}
+		}
+	}
 }
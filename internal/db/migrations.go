@@ -0,0 +1,236 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"math"
+	"time"
+)
+
+// BooksSchema and ProgressSchema are the databases that a migration changes
+const BooksSchema = "books"
+const ProgressSchema = "progress"
+
+// migration is recorded only after it is complete because mysql can't roll back schema changes
+type migration struct {
+	version int
+	name    string
+	schema  string
+	up      func(ctx context.Context, db *sql.DB)
+	down    func(ctx context.Context, db *sql.DB)
+}
+
+// migrateUpHint tells how to create the databases and apply the migrations that are missing
+const migrateUpHint = "run the migrate utility with the up command: go run ./cmd/utilities/migrate up"
+
+// migrations are never changed after they are released, schema changes are made by adding a new migration at the end
+var migrations = []migration{
+	{1, "create_progress_tables", ProgressSchema, createProgressTables, dropProgressTables},
+	{2, "create_book_tables", BooksSchema, createBookTables, dropBookTables},
+	{3, "create_open_library_id_column", BooksSchema, createOpenLibraryIdColumn, dropOpenLibraryIdColumn},
+	{4, "create_open_library_ratings_table", BooksSchema, createOpenLibraryRatingsTable, dropOpenLibraryRatingsTable},
+	{5, "create_open_library_reading_logs_table", BooksSchema, createOpenLibraryReadingLogsTable, dropOpenLibraryReadingLogsTable},
+	{6, "create_books_updated_at_column", BooksSchema, createBooksUpdatedAtColumn, dropBooksUpdatedAtColumn},
+	{7, "create_book_changes_table", BooksSchema, createBookChangesTable, dropBookChangesTable},
+	{8, "dedupe_books", BooksSchema, dedupeBooks, func(ctx context.Context, db *sql.DB) {}},
+	{9, "create_books_unique_keys", BooksSchema, createBooksUniqueKeys, dropBooksUniqueKeys},
+	{10, "create_link_keys", BooksSchema, createLinkKeys, dropLinkKeys},
+	{11, "create_book_prices_table", BooksSchema, createBookPricesTable, dropBookPricesTable},
+	{12, "create_other_isbns_table", BooksSchema, createOtherIsbnsTable, dropOtherIsbnsTable},
+	{13, "create_reviews_table", BooksSchema, createReviewsTable, dropReviewsTable},
+	{14, "create_book_dimensions_table", BooksSchema, createBookDimensionsTable, dropBookDimensionsTable},
+	{15, "create_book_images_table", BooksSchema, createBookImagesTable, dropBookImagesTable},
+	{16, "create_book_series_table", BooksSchema, createBookSeriesTable, dropBookSeriesTable},
+	{17, "create_book_access_table", BooksSchema, createBookAccessTable, dropBookAccessTable},
+	{18, "create_api_responses_table", ProgressSchema, createApiResponsesTable, dropApiResponsesTable},
+	{19, "normalize_book_isbns", BooksSchema, dedupeBookIsbns, func(ctx context.Context, db *sql.DB) {}},
+	{20, "create_published_date_columns", BooksSchema, createPublishedDateColumns, dropPublishedDateColumns},
+	{21, "create_language_aliases_table", BooksSchema, createLanguageAliasesTable, dropLanguageAliasesTable},
+	{22, "normalize_languages", BooksSchema, normalizeLanguages, func(ctx context.Context, db *sql.DB) {}},
+	{23, "create_authors_name_key_column", BooksSchema, createAuthorsNameKeyColumn, dropAuthorsNameKeyColumn},
+	{24, "create_author_aliases_table", BooksSchema, createAuthorAliasesTable, dropAuthorAliasesTable},
+	{25, "normalize_authors", BooksSchema, normalizeAuthors, func(ctx context.Context, db *sql.DB) {}},
+	{26, "create_authors_name_key_unique_key", BooksSchema, createAuthorsNameKeyUniqueKey, dropAuthorsNameKeyUniqueKey},
+}
+
+type MigrationStatus struct {
+	Version   int
+	Name      string
+	Schema    string
+	AppliedAt time.Time
+	IsApplied bool
+}
+
+// CheckSchemaVersion stops the program if the schema doesn't have exactly the migrations this version expects
+func CheckSchemaVersion(ctx context.Context, db *sql.DB, schema string) {
+	if !hasSchema(ctx, db) {
+		log.Fatal(fmt.Sprintf("The %v database doesn't exist, %v", schema, migrateUpHint))
+	}
+
+	appliedMigrations := getAppliedMigrations(ctx, db)
+
+	for _, migration := range migrations {
+		if migration.schema != schema {
+			continue
+		}
+
+		_, isApplied := appliedMigrations[migration.version]
+		if !isApplied {
+			log.Fatal(fmt.Sprintf("The %v database is missing migration %v %v, %v", schema, migration.version, migration.name, migrateUpHint))
+		}
+
+		delete(appliedMigrations, migration.version)
+	}
+
+	// in sqlite both schemas share the migrations table
+	for version := range appliedMigrations {
+		if getMigration(version).schema == "" {
+			log.Fatal(fmt.Sprintf("The %v database has migration %v that is not known, update to a newer version", schema, version))
+		}
+	}
+}
+
+func GetMigrationStatus(ctx context.Context, booksDb *sql.DB, progressDb *sql.DB) []MigrationStatus {
+	appliedBooksMigrations := getAppliedMigrations(ctx, booksDb)
+	appliedProgressMigrations := getAppliedMigrations(ctx, progressDb)
+
+	statuses := make([]MigrationStatus, 0, len(migrations))
+	for _, migration := range migrations {
+		appliedMigrations := appliedBooksMigrations
+		if migration.schema == ProgressSchema {
+			appliedMigrations = appliedProgressMigrations
+		}
+
+		appliedAt, isApplied := appliedMigrations[migration.version]
+		statuses = append(statuses, MigrationStatus{
+			Version:   migration.version,
+			Name:      migration.name,
+			Schema:    migration.schema,
+			AppliedAt: appliedAt,
+			IsApplied: isApplied,
+		})
+	}
+
+	return statuses
+}
+
+// MigrateUp applies the migrations that are not applied yet and returns how many were applied
+func MigrateUp(ctx context.Context, booksDb *sql.DB, progressDb *sql.DB) int {
+	return migrateUpTo(ctx, booksDb, progressDb, math.MaxInt)
+}
+
+// migrateUpTo applies the migrations that are not applied yet up to the version
+func migrateUpTo(ctx context.Context, booksDb *sql.DB, progressDb *sql.DB, version int) int {
+	createMigrationsTable(ctx, booksDb)
+	createMigrationsTable(ctx, progressDb)
+
+	appliedCount := 0
+	for _, status := range GetMigrationStatus(ctx, booksDb, progressDb) {
+		if status.IsApplied || status.Version > version {
+			continue
+		}
+
+		migration := getMigration(status.Version)
+		db := getSchemaDatabase(migration.schema, booksDb, progressDb)
+
+		fmt.Printf("Applying migration %v %v...\n", migration.version, migration.name)
+		migration.up(ctx, db)
+
+		_, err := db.ExecContext(ctx, rebind(`INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)`), migration.version, migration.name, time.Now().UTC())
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		appliedCount++
+	}
+
+	return appliedCount
+}
+
+// MigrateDown reverts the last applied migration and returns false if there was none
+func MigrateDown(ctx context.Context, booksDb *sql.DB, progressDb *sql.DB) bool {
+	createMigrationsTable(ctx, booksDb)
+	createMigrationsTable(ctx, progressDb)
+
+	statuses := GetMigrationStatus(ctx, booksDb, progressDb)
+	for i := len(statuses) - 1; i >= 0; i-- {
+		if !statuses[i].IsApplied {
+			continue
+		}
+
+		migration := getMigration(statuses[i].Version)
+		db := getSchemaDatabase(migration.schema, booksDb, progressDb)
+
+		fmt.Printf("Reverting migration %v %v...\n", migration.version, migration.name)
+		migration.down(ctx, db)
+
+		_, err := db.ExecContext(ctx, rebind(`DELETE FROM schema_migrations WHERE version = ?`), migration.version)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		return true
+	}
+
+	return false
+}
+
+// getAppliedMigrations is empty if the database or the migrations table don't exist yet
+func getAppliedMigrations(ctx context.Context, db *sql.DB) map[int]time.Time {
+	appliedMigrations := make(map[int]time.Time)
+	if !hasSchema(ctx, db) || !hasTable(ctx, db, "schema_migrations") {
+		return appliedMigrations
+	}
+
+	rows, err := db.QueryContext(ctx, `SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			log.Fatal(err)
+		}
+	}(rows)
+
+	var version int
+	var appliedAt time.Time
+
+	for rows.Next() {
+		err := rows.Scan(&version, &appliedAt)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		appliedMigrations[version] = appliedAt
+	}
+
+	return appliedMigrations
+}
+
+func createMigrationsTable(ctx context.Context, db *sql.DB) {
+	_, err := db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (version INTEGER PRIMARY KEY, name VARCHAR(255), applied_at `+timestamp()+`);`)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func getMigration(version int) migration {
+	for _, migration := range migrations {
+		if migration.version == version {
+			return migration
+		}
+	}
+
+	return migration{}
+}
+
+func getSchemaDatabase(schema string, booksDb *sql.DB, progressDb *sql.DB) *sql.DB {
+	if schema == ProgressSchema {
+		return progressDb
+	}
+
+	return booksDb
+}
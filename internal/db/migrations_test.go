@@ -0,0 +1,154 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+)
+
+func TestMigrations(t *testing.T) {
+	forEachDriver(t, func(t *testing.T, databases testDatabases) {
+		ctx := context.Background()
+
+		appliedCount := MigrateUp(ctx, databases.booksDb, databases.progressDb)
+		if appliedCount != len(migrations) {
+			t.Fatalf("applied %v migrations, want %v", appliedCount, len(migrations))
+		}
+
+		CheckSchemaVersion(ctx, databases.booksDb, BooksSchema)
+		CheckSchemaVersion(ctx, databases.progressDb, ProgressSchema)
+
+		appliedCount = MigrateUp(ctx, databases.booksDb, databases.progressDb)
+		if appliedCount != 0 {
+			t.Errorf("applied %v migrations again, want 0", appliedCount)
+		}
+
+		revertedCount := 0
+		for MigrateDown(ctx, databases.booksDb, databases.progressDb) {
+			revertedCount++
+		}
+		if revertedCount != len(migrations) {
+			t.Errorf("reverted %v migrations, want %v", revertedCount, len(migrations))
+		}
+
+		appliedCount = MigrateUp(ctx, databases.booksDb, databases.progressDb)
+		if appliedCount != len(migrations) {
+			t.Errorf("applied %v migrations after reverting them, want %v", appliedCount, len(migrations))
+		}
+	})
+}
+
+func TestGetMigrationStatusOfANewDatabase(t *testing.T) {
+	forEachDriver(t, func(t *testing.T, databases testDatabases) {
+		ctx := context.Background()
+
+		for _, status := range GetMigrationStatus(ctx, databases.booksDb, databases.progressDb) {
+			if status.IsApplied {
+				t.Errorf("migration %v is applied, want none", status.Version)
+			}
+		}
+
+		if hasTable(ctx, databases.booksDb, "schema_migrations") || hasTable(ctx, databases.progressDb, "schema_migrations") {
+			t.Error("the status created the migrations table")
+		}
+	})
+}
+
+// seedQuery is a row that a test inserts, written with ? placeholders
+type seedQuery struct {
+	query string
+	args  []any
+}
+
+// TestMigrationsMergeDuplicates seeds duplicates before each migration that merges books, with only the tables that
+// existed at that version, and checks that the migrations up to the latest version merge them
+func TestMigrationsMergeDuplicates(t *testing.T) {
+	// duplicates saved before the unique keys of migration 9
+	beforeDedupe := []seedQuery{
+		{`INSERT INTO books (id, title, isbn13) VALUES (?, ?, ?)`, []any{1, "The Hobbit", "9780261103344"}},
+		{`INSERT INTO books (id, title, isbn13, date_published, pages) VALUES (?, ?, ?, ?, ?)`, []any{2, "", "9780261103344", "1995", 310}},
+		{`INSERT INTO books (id, title, google_id) VALUES (?, ?, ?)`, []any{3, "The Lord of the Rings", "pD6arNyKyi8C"}},
+		{`INSERT INTO books (id, title, google_id) VALUES (?, ?, ?)`, []any{4, "The Lord of the Rings", "pD6arNyKyi8C"}},
+		{`INSERT INTO books (id, title, isbn13, open_library_id) VALUES (?, ?, ?, ?)`, []any{5, "Unfinished Tales", "", "OL1M"}},
+		{`INSERT INTO authors (id, name) VALUES (?, ?)`, []any{1, "J. R. R. Tolkien"}},
+		{`INSERT INTO author_book (author_id, book_id) VALUES (?, ?)`, []any{1, 1}},
+		{`INSERT INTO author_book (author_id, book_id) VALUES (?, ?)`, []any{1, 2}},
+		{`INSERT INTO author_book (author_id, book_id) VALUES (?, ?)`, []any{1, 2}},
+		{`INSERT INTO subjects (id, name) VALUES (?, ?)`, []any{1, "Fiction"}},
+		{`INSERT INTO book_subject (book_id, subject_id) VALUES (?, ?)`, []any{2, 1}},
+		{`INSERT INTO industry_identifiers (type, identifier, book_id) VALUES (?, ?, ?)`, []any{"ISBN_13", "9780261103344", 1}},
+		{`INSERT INTO industry_identifiers (type, identifier, book_id) VALUES (?, ?, ?)`, []any{"ISBN_13", "9780261103344", 2}},
+		{`INSERT INTO open_library_ratings (rating, date, book_id) VALUES (?, ?, ?)`, []any{4.5, "2024-01-01", 2}},
+		{`INSERT INTO book_changes (book_id, field, old_value, new_value) VALUES (?, ?, ?, ?)`, []any{4, "title", "", "The Lord of the Rings"}},
+	}
+
+	// isbns written differently that are only the same once they are normalized by migration 19
+	beforeIsbnDedupe := []seedQuery{
+		{`INSERT INTO books (id, title, isbn13) VALUES (?, ?, ?)`, []any{6, "The Silmarillion", "978-0-261-10273-6"}},
+		{`INSERT INTO books (id, title, isbn, date_published) VALUES (?, ?, ?, ?)`, []any{7, "The Silmarillion", "0261102737", "1999-10-04"}},
+		{`INSERT INTO author_book (author_id, book_id) VALUES (?, ?)`, []any{1, 7}},
+		{`INSERT INTO book_dimensions (book_id, length, length_unit) VALUES (?, ?, ?)`, []any{6, 20, "cm"}},
+		{`INSERT INTO book_dimensions (book_id, length, length_unit) VALUES (?, ?, ?)`, []any{7, 8, "in"}},
+		{`INSERT INTO book_prices (book_id, merchant, price) VALUES (?, ?, ?)`, []any{7, "Bookshop", 9.99}},
+		{`INSERT INTO other_isbns (book_id, isbn, binding) VALUES (?, ?, ?)`, []any{6, "9780618391110", "Paperback"}},
+		{`INSERT INTO other_isbns (book_id, isbn, binding) VALUES (?, ?, ?)`, []any{7, "9780618391110", "Paperback"}},
+		{`INSERT INTO other_isbns (book_id, isbn, binding) VALUES (?, ?, ?)`, []any{7, "9780048231536", "Hardcover"}},
+		{`INSERT INTO reviews (book_id, review) VALUES (?, ?)`, []any{6, "A history of the elder days."}},
+		{`INSERT INTO reviews (book_id, review) VALUES (?, ?)`, []any{7, "A history of the elder days."}},
+		{`INSERT INTO book_images (book_id, size, url) VALUES (?, ?, ?)`, []any{6, "thumbnail", "https://example.com/silmarillion.jpg"}},
+		{`INSERT INTO book_images (book_id, size, url) VALUES (?, ?, ?)`, []any{7, "thumbnail", "https://example.com/silmarillion.jpg"}},
+		{`INSERT INTO book_series (book_id, series_id, series_book_type, order_number) VALUES (?, ?, ?, ?)`, []any{6, "legendarium", "main", 1}},
+		{`INSERT INTO book_series (book_id, series_id, series_book_type, order_number) VALUES (?, ?, ?, ?)`, []any{7, "legendarium", "main", 1}},
+	}
+
+	counts := map[string]int{
+		`books`: 4,
+		`books WHERE id = 1 AND title = 'The Hobbit' AND pages = 310 AND date_published = '1995' AND published_year = 1995`: 1,
+		`books WHERE id = 3 AND google_id = 'pD6arNyKyi8C'`:                                                                 1,
+		`books WHERE id = 5 AND isbn13 IS NULL`:                                                                             1,
+		`books WHERE id = 6 AND isbn13 = '9780261102736' AND isbn = '0261102737' AND published_year = 1999`:                 1,
+		`author_book`:                              2,
+		`book_subject WHERE book_id = 1`:           1,
+		`industry_identifiers`:                     1,
+		`open_library_ratings WHERE book_id = 1`:   1,
+		`book_changes WHERE book_id = 3`:           1,
+		`book_dimensions`:                          1,
+		`book_dimensions WHERE length_unit = 'cm'`: 1,
+		`book_prices WHERE book_id = 6`:            1,
+		`other_isbns WHERE book_id = 6`:            2,
+		`reviews WHERE book_id = 6`:                1,
+		`book_images WHERE book_id = 6`:            1,
+		`book_series WHERE book_id = 6`:            1,
+	}
+
+	forEachDriver(t, func(t *testing.T, databases testDatabases) {
+		ctx := context.Background()
+
+		migrateUpTo(ctx, databases.booksDb, databases.progressDb, 7)
+		seed(t, databases.booksDb, beforeDedupe)
+
+		migrateUpTo(ctx, databases.booksDb, databases.progressDb, 18)
+		seed(t, databases.booksDb, beforeIsbnDedupe)
+
+		MigrateUp(ctx, databases.booksDb, databases.progressDb)
+		CheckSchemaVersion(ctx, databases.booksDb, BooksSchema)
+
+		for query, want := range counts {
+			count := countRows(t, databases.booksDb, query)
+			if count != want {
+				t.Errorf("%v: %v rows, want %v", query, count, want)
+			}
+		}
+	})
+}
+
+func seed(t *testing.T, db *sql.DB, queries []seedQuery) {
+	t.Helper()
+
+	for _, query := range queries {
+		_, err := db.Exec(rebind(query.query), query.args...)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
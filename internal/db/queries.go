@@ -3,15 +3,28 @@ package db
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"log"
 	"slices"
 	"strings"
+	"time"
 
-	"github.com/zaelmyth/book-data-collector/google"
-	"github.com/zaelmyth/book-data-collector/isbndb"
+	"github.com/zaelmyth/book-data-collector/internal/authorname"
+	"github.com/zaelmyth/book-data-collector/internal/isbn"
+	"github.com/zaelmyth/book-data-collector/internal/language"
+	"github.com/zaelmyth/book-data-collector/internal/provider"
+	"github.com/zaelmyth/book-data-collector/internal/pubdate"
 )
 
+const IsbnFound = "found"
+const IsbnNotFound = "not_found"
+const IsbnError = "error"
+
+// maxPlaceholders is below the lowest limit of placeholders per statement of the supported databases, which is the
+// 32766 of sqlite
+const maxPlaceholders = 30000
+
 func GetSavedData(ctx context.Context, db *sql.DB, tableName string, columnName string) map[string]struct{} {
 	rows, err := db.QueryContext(ctx, `SELECT `+columnName+` FROM `+tableName)
 	if err != nil {
@@ -71,250 +84,914 @@ func GetSavedDataWithId(ctx context.Context, db *sql.DB, tableName string, colum
 	return savedData
 }
 
-func SaveBook(ctx context.Context, db *sql.DB, book isbndb.Book, savedData SavedData) {
-	book.Isbn13 = fmt.Sprintf("%.*s", 500, strings.TrimSpace(book.Isbn13))
-	book.Synopsis = fmt.Sprintf("%.*s", 10000, book.Synopsis)
+// SaveBooks saves a page of books with their relations in a transaction so that a book is never saved without all of
+// them. Each table is written with as few statements as possible because a page can have thousands of books. Books
+// that are already saved are skipped, or updated if they are refreshed.
+func SaveBooks(ctx context.Context, db *sql.DB, idColumn string, records []provider.Record, savedData SavedData, isRefresh bool) {
+	SaveBooksAt(ctx, db, idColumn, records, savedData, isRefresh, time.Now().UTC())
+}
 
-	if savedData.IsBookSaved(book.Isbn13) {
-		return
+// SaveBooksAt saves the books like SaveBooks as they were when they were collected at savedAt, which is the time of
+// their prices and changes. It's used for books that are saved after they were collected, like the ones of archived
+// responses.
+func SaveBooksAt(ctx context.Context, db *sql.DB, idColumn string, records []provider.Record, savedData SavedData, isRefresh bool, savedAt time.Time) {
+	validIdColumns := []string{"isbn13", "google_id", "open_library_id"}
+	if !slices.Contains(validIdColumns, idColumn) {
+		log.Fatal("Invalid id column")
 	}
 
-	savedData.AddBookToMemory(book.Isbn13) // add it early in case it comes up in another concurrent search
-
-	publisher := fmt.Sprintf("%.*s", 500, strings.TrimSpace(book.Publisher))
-	publisherId := savedData.SavePublisher(ctx, db, publisher)
-
-	language := fmt.Sprintf("%.*s", 500, strings.TrimSpace(book.Language))
-	languageId := savedData.SaveLanguage(ctx, db, language)
+	var newBooks, savedBooks []provider.Record
+	for _, record := range records {
+		record = normalizeRecord(record)
 
-	bookId := insertBook(ctx, db, book, publisherId, languageId)
-
-	for _, author := range book.Authors {
-		author := fmt.Sprintf("%.*s", 500, strings.TrimSpace(author))
-		authorId := savedData.SaveAuthor(ctx, db, author)
-
-		_, err := db.ExecContext(ctx, `INSERT INTO author_book (author_id, book_id) VALUES (?, ?)`, authorId, bookId)
-		if err != nil {
-			log.Fatal(err)
+		// books without an id can't be told apart, reserve the rest in case they come up in another concurrent search
+		if record.Id == "" {
+			continue
 		}
-	}
 
-	for _, subject := range book.Subjects {
-		subject := fmt.Sprintf("%.*s", 500, strings.TrimSpace(subject))
-		subjectId := savedData.SaveSubject(ctx, db, subject)
+		isReserved, isSaved := savedData.ReserveBook(record.Id, isRefresh)
+		if !isReserved {
+			continue
+		}
 
-		_, err := db.ExecContext(ctx, `INSERT INTO book_subject (book_id, subject_id) VALUES (?, ?)`, bookId, subjectId)
-		if err != nil {
-			log.Fatal(err)
+		if isSaved {
+			savedBooks = append(savedBooks, record)
+		} else {
+			newBooks = append(newBooks, record)
 		}
 	}
-}
 
-func SaveVolume(ctx context.Context, db *sql.DB, volume google.Volume, savedData SavedData) {
-	if savedData.IsBookSaved(volume.Id) {
+	books := slices.Concat(newBooks, savedBooks)
+	if len(books) == 0 {
 		return
 	}
 
-	savedData.AddBookToMemory(volume.Id) // add it early in case it comes up in another concurrent search
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Fatal(err)
+	}
 
-	publisher := fmt.Sprintf("%.*s", 500, strings.TrimSpace(volume.VolumeInfo.Publisher))
-	publisherId := savedData.SavePublisher(ctx, db, publisher)
+	ids, newIds := saveLookups(ctx, tx, books, savedData)
+	insertLanguageAliases(ctx, tx, books, ids)
+	newAuthorAliases := insertAuthorAliases(ctx, tx, books, ids, savedData)
 
-	language := fmt.Sprintf("%.*s", 500, strings.TrimSpace(volume.VolumeInfo.Language))
-	languageId := savedData.SaveLanguage(ctx, db, language)
+	bookIds := insertBooks(ctx, tx, idColumn, newBooks, ids, savedAt)
+	insertAuthorBooks(ctx, tx, newBooks, bookIds, ids)
+	insertBookSubjects(ctx, tx, newBooks, bookIds, ids)
+	insertIndustryIdentifiers(ctx, tx, newBooks, bookIds)
+	insertOtherIsbns(ctx, tx, newBooks, bookIds)
+	insertReviews(ctx, tx, newBooks, bookIds)
+	insertBookDimensions(ctx, tx, newBooks, bookIds)
+	insertBookImages(ctx, tx, newBooks, bookIds)
+	insertBookSeries(ctx, tx, newBooks, bookIds)
+	insertBookAccess(ctx, tx, newBooks, bookIds)
 
-	bookId := insertVolume(ctx, db, volume, publisherId, languageId)
+	savedBookIds := updateBooks(ctx, tx, idColumn, savedBooks, ids, savedAt)
 
-	for _, author := range volume.VolumeInfo.Authors {
-		author := fmt.Sprintf("%.*s", 500, strings.TrimSpace(author))
-		authorId := savedData.SaveAuthor(ctx, db, author)
+	insertPrices(ctx, tx, newBooks, bookIds, savedAt)
+	insertPrices(ctx, tx, savedBooks, savedBookIds, savedAt)
 
-		_, err := db.ExecContext(ctx, `INSERT INTO author_book (author_id, book_id) VALUES (?, ?)`, authorId, bookId)
-		if err != nil {
-			log.Fatal(err)
-		}
+	err = tx.Commit()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	memoryBookIds := make([]string, 0, len(books))
+	for _, book := range books {
+		memoryBookIds = append(memoryBookIds, book.Id)
 	}
 
-	for _, subject := range volume.VolumeInfo.Categories {
-		subject := fmt.Sprintf("%.*s", 500, strings.TrimSpace(subject))
-		subjectId := savedData.SaveSubject(ctx, db, subject)
+	savedData.AddBooksToMemory(memoryBookIds, newIds)
+	savedData.addAuthorAliasesToMemory(newAuthorAliases)
+}
 
-		_, err := db.ExecContext(ctx, `INSERT INTO book_subject (book_id, subject_id) VALUES (?, ?)`, bookId, subjectId)
+// GetBookIds returns at most limit ids of the books after the book with the id and the id of the last one
+func GetBookIds(ctx context.Context, db *sql.DB, idColumn string, afterId int, limit int) ([]string, int) {
+	rows, err := db.QueryContext(
+		ctx,
+		rebind(`SELECT id, `+idColumn+` FROM books WHERE id > ? AND `+idColumn+` IS NOT NULL AND `+idColumn+` <> '' ORDER BY id LIMIT ?`),
+		afterId,
+		limit,
+	)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
 		if err != nil {
 			log.Fatal(err)
 		}
-	}
+	}(rows)
+
+	var ids []string
+	lastId := afterId
+	var id string
 
-	for _, industryIdentifier := range volume.VolumeInfo.IndustryIdentifiers {
-		_, err := db.ExecContext(ctx, `INSERT INTO industry_identifiers (type, identifier, book_id) VALUES (?, ?, ?)`, industryIdentifier.Type, industryIdentifier.Identifier, bookId)
+	for rows.Next() {
+		err := rows.Scan(&lastId, &id)
 		if err != nil {
 			log.Fatal(err)
 		}
+
+		ids = append(ids, id)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		log.Fatal(err)
 	}
+
+	return ids, lastId
+}
+
+func CountBookIds(ctx context.Context, db *sql.DB, idColumn string) int {
+	var count int
+	err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM books WHERE `+idColumn+` IS NOT NULL AND `+idColumn+` <> ''`).Scan(&count)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	return count
 }
 
 func UpdateOpenLibraryIdColumn(ctx context.Context, db *sql.DB, id int, openLibraryId string) {
-	_, err := db.ExecContext(ctx, `UPDATE books SET open_library_id = ? WHERE id = ?`, openLibraryId, id)
+	_, err := db.ExecContext(ctx, rebind(`UPDATE books SET open_library_id = ? WHERE id = ?`), openLibraryId, id)
 	if err != nil {
 		log.Fatal(err)
 	}
 }
 
 func SaveOpenLibraryRatings(ctx context.Context, db *sql.DB, rating float64, date string, bookId int) {
-	_, err := db.ExecContext(ctx, `INSERT INTO open_library_ratings (rating, date, book_id) VALUES (?, ?, ?)`, rating, date, bookId)
+	_, err := db.ExecContext(ctx, rebind(`INSERT INTO open_library_ratings (rating, date, book_id) VALUES (?, ?, ?)`), rating, date, bookId)
 	if err != nil {
 		log.Fatal(err)
 	}
 }
 
 func SaveOpenLibraryReadingLogs(ctx context.Context, db *sql.DB, status string, date string, bookId int) {
-	_, err := db.ExecContext(ctx, `INSERT INTO open_library_reading_logs (status, date, book_id) VALUES (?, ?, ?)`, status, date, bookId)
+	_, err := db.ExecContext(ctx, rebind(`INSERT INTO open_library_reading_logs (status, date, book_id) VALUES (?, ?, ?)`), status, date, bookId)
 	if err != nil {
 		log.Fatal(err)
 	}
 }
 
-func insertData(ctx context.Context, db *sql.DB, tableName string, name string) int {
-	validateTableNames := []string{"authors", "subjects", "publishers", "languages"}
-	if !slices.Contains(validateTableNames, tableName) {
-		log.Fatal("Invalid table name")
+func GetApiCalls(ctx context.Context, db *sql.DB, provider string, day string) int {
+	var calls int
+	err := db.QueryRowContext(ctx, rebind(`SELECT calls FROM api_calls WHERE provider = ? AND day = ?`), provider, day).Scan(&calls)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0
+	}
+	if err != nil {
+		log.Fatal(err)
 	}
 
-	result, err := db.ExecContext(ctx, `INSERT INTO `+tableName+` (name) VALUES (?)`, name)
+	return calls
+}
+
+func AddApiCall(ctx context.Context, db *sql.DB, provider string, day string) {
+	result, err := db.ExecContext(ctx, rebind(`UPDATE api_calls SET calls = calls + 1 WHERE provider = ? AND day = ?`), provider, day)
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	id, err := result.LastInsertId()
+	updatedRows, err := result.RowsAffected()
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	return int(id)
-}
-
-func insertBook(ctx context.Context, db *sql.DB, book isbndb.Book, publisherId int, languageId int) int {
-	result, err := db.ExecContext(ctx, `INSERT INTO books
-		(
-			title, 
-			title_long,
-			isbn,
-			isbn13,
-			dewey_decimal,
-			binding,
-			publisher_id,
-			language_id,
-			date_published,
-			edition,
-			pages,
-			dimensions,
-			overview,
-			image,
-			msrp,
-			excerpt,
-			synopsis,
-			related_type
-		) VALUES (
-			?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?
-		)`,
+	if updatedRows == 0 {
+		_, err = db.ExecContext(ctx, rebind(`INSERT INTO api_calls (provider, day, calls) VALUES (?, ?, 1)`), provider, day)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+}
+
+func GetQueryProgress(ctx context.Context, db *sql.DB) map[string]QueryProgress {
+	rows, err := db.QueryContext(ctx, `SELECT query, total, last_page FROM query_progress`)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			log.Fatal(err)
+		}
+	}(rows)
+
+	queryProgress := make(map[string]QueryProgress)
+	var query string
+	var progress QueryProgress
+
+	for rows.Next() {
+		err := rows.Scan(&query, &progress.Total, &progress.LastPage)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		queryProgress[query] = progress
+	}
+
+	return queryProgress
+}
+
+// GetSearchedIsbns returns the isbns that were found and the ones that were not found after recheckNotFoundAfter. Not
+// found isbns are never rechecked if recheckNotFoundAfter is zero.
+func GetSearchedIsbns(ctx context.Context, db *sql.DB, recheckNotFoundAfter time.Time) map[string]struct{} {
+	query := `SELECT isbn FROM searched_isbns WHERE status = ? OR status = ?`
+	args := []any{IsbnFound, IsbnNotFound}
+	if !recheckNotFoundAfter.IsZero() {
+		query = `SELECT isbn FROM searched_isbns WHERE status = ? OR (status = ? AND searched_at > ?)`
+		args = append(args, recheckNotFoundAfter)
+	}
+
+	rows, err := db.QueryContext(ctx, rebind(query), args...)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			log.Fatal(err)
+		}
+	}(rows)
+
+	isbns := make(map[string]struct{})
+	var searchedIsbn string
+
+	for rows.Next() {
+		err := rows.Scan(&searchedIsbn)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		// isbns used to be saved as they were written in the input
+		isbn13, isValid := isbn.To13(searchedIsbn)
+		if isValid {
+			searchedIsbn = isbn13
+		}
+
+		isbns[searchedIsbn] = struct{}{}
+	}
+
+	return isbns
+}
+
+// saveLookups returns the ids of all the lookup names of the books and the ids of the names that were not in memory.
+// Every transaction inserts the names of the tables in the same order and sorted so that concurrent transactions
+// waiting for the same names can't deadlock. The ids are selected after all the inserts because a mysql transaction
+// doesn't see rows that were committed after its first read, and a name can be committed by another transaction
+// while this one waits for it. Authors are looked up by the key of their name and saved with the normalized name of
+// the first way they are written.
+func saveLookups(ctx context.Context, tx *sql.Tx, books []provider.Record, savedData SavedData) (lookupIds, lookupIds) {
+	names := make(map[string][]string)
+	authorNames := make(map[string]string)
+	for _, book := range books {
+		names["publishers"] = append(names["publishers"], book.Publisher)
+		if book.Language != "" {
+			names["languages"] = append(names["languages"], languageName(book.Language))
+		}
+		names["subjects"] = append(names["subjects"], book.Subjects...)
+
+		for _, author := range book.Authors {
+			key := authorname.Key(author)
+			if key == "" {
+				continue
+			}
+
+			names["authors"] = append(names["authors"], key)
+			if authorNames[key] == "" {
+				authorNames[key] = authorname.Normalize(author)
+			}
+		}
+	}
+
+	lookupTables := []string{"publishers", "languages", "authors", "subjects"}
+	ids := make(lookupIds)
+	missingNames := make(map[string][]string)
+
+	for _, tableName := range lookupTables {
+		slices.Sort(names[tableName])
+		ids[tableName], missingNames[tableName] = savedData.getLookupIds(tableName, slices.Compact(names[tableName]))
+
+		rows := make([][]any, 0, len(missingNames[tableName]))
+		for _, name := range missingNames[tableName] {
+			if tableName == "authors" {
+				rows = append(rows, []any{name, authorNames[name]})
+			} else {
+				rows = append(rows, []any{name})
+			}
+		}
+
+		if tableName == "authors" {
+			insertRows(ctx, tx, `INSERT INTO authors (name_key, name) VALUES `, rows, onConflictIgnore("name_key"))
+		} else {
+			insertRows(ctx, tx, `INSERT INTO `+tableName+` (name) VALUES `, rows, onConflictIgnore("name"))
+		}
+	}
+
+	newIds := make(lookupIds)
+	for _, tableName := range lookupTables {
+		newIds[tableName] = selectIds(ctx, tx, tableName, lookupColumn(tableName), missingNames[tableName])
+		for name, id := range newIds[tableName] {
+			ids[tableName][name] = id
+		}
+	}
+
+	return ids, newIds
+}
+
+// lookupColumn is the column that the rows of a lookup table are looked up by
+func lookupColumn(tableName string) string {
+	if tableName == "authors" {
+		return "name_key"
+	}
+
+	return "name"
+}
+
+// insertAuthorAliases keeps every way that the apis wrote the authors as aliases of the saved authors and returns the
+// aliases that were not in memory
+func insertAuthorAliases(ctx context.Context, tx *sql.Tx, books []provider.Record, ids lookupIds, savedData SavedData) []string {
+	var aliases []string
+	for _, book := range books {
+		for _, author := range book.Authors {
+			if authorname.Key(author) != "" {
+				aliases = append(aliases, author)
+			}
+		}
+	}
+
+	// sorted for the same reason as the lookup names
+	slices.Sort(aliases)
+	missingAliases := savedData.getMissingAuthorAliases(slices.Compact(aliases))
+
+	rows := make([][]any, 0, len(missingAliases))
+	for _, alias := range missingAliases {
+		rows = append(rows, []any{alias, ids["authors"][authorname.Key(alias)]})
+	}
+
+	insertRows(ctx, tx, `INSERT INTO author_aliases (alias, author_id) VALUES `, rows, onConflictIgnore("alias"))
+
+	return missingAliases
+}
+
+// languageName returns the name that a language is saved with, which is its ISO 639 code or the value as the api wrote
+// it if it's not a known language
+func languageName(value string) string {
+	code, isKnown := language.Normalize(value)
+	if !isKnown {
+		return value
+	}
+
+	return code
+}
+
+// insertLanguageAliases keeps the values that the apis wrote the languages with as aliases of the saved languages
+func insertLanguageAliases(ctx context.Context, tx *sql.Tx, books []provider.Record, ids lookupIds) {
+	var aliases []string
+	for _, book := range books {
+		if book.Language != "" && book.Language != languageName(book.Language) {
+			aliases = append(aliases, book.Language)
+		}
+	}
+
+	// sorted for the same reason as the lookup names
+	slices.Sort(aliases)
+
+	rows := make([][]any, 0, len(aliases))
+	for _, alias := range slices.Compact(aliases) {
+		rows = append(rows, []any{alias, ids["languages"][languageName(alias)]})
+	}
+
+	insertRows(ctx, tx, `INSERT INTO language_aliases (alias, language_id) VALUES `, rows, onConflictIgnore("alias"))
+}
+
+// bookColumns are the books table columns that are saved from a record in the order of getBookValues
+var bookColumns = []string{
+	"google_id",
+	"title",
+	"title_long",
+	"subtitle",
+	"isbn",
+	"isbn13",
+	"dewey_decimal",
+	"binding",
+	"publisher_id",
+	"language_id",
+	"date_published",
+	"edition",
+	"pages",
+	"dimensions",
+	"overview",
+	"image",
+	"msrp",
+	"excerpt",
+	"synopsis",
+	"related_type",
+	"average_rating",
+	"rating_count",
+	"main_category",
+	"open_library_id",
+}
+
+func getBookValues(book provider.Record, ids lookupIds) []any {
+	return []any{
+		nullIfEmpty(book.GoogleId),
 		book.Title,
 		book.TitleLong,
-		book.Isbn,
-		book.Isbn13,
-		strings.Join(book.DeweyDecimal, ", "),
+		book.Subtitle,
+		nullIfEmpty(book.Isbn),
+		nullIfEmpty(book.Isbn13),
+		book.DeweyDecimal,
 		book.Binding,
-		publisherId,
-		languageId,
+		ids["publishers"][book.Publisher],
+		nullIfZeroInt(ids["languages"][languageName(book.Language)]),
 		book.DatePublished,
 		book.Edition,
 		book.Pages,
-		book.Dimensions,
+		nullIfEmpty(book.Dimensions),
 		book.Overview,
 		book.Image,
 		book.Msrp,
 		book.Excerpt,
 		book.Synopsis,
-		book.Related.Type,
-	)
+		book.RelatedType,
+		book.AverageRating,
+		book.RatingCount,
+		book.MainCategory,
+		nullIfEmpty(book.OpenLibraryId),
+	}
+}
+
+// publishedDateColumns are parsed from date_published, which keeps the date as the api wrote it. They are saved
+// whenever date_published is saved and are not compared by refresh because they can't change on their own.
+var publishedDateColumns = []string{
+	"published_year",
+	"published_month",
+	"published_day",
+	"published_date_precision",
+}
+
+func getPublishedDateValues(datePublished string) []any {
+	date := pubdate.Parse(datePublished)
+
+	return []any{
+		nullIfZeroInt(date.Year),
+		nullIfZeroInt(date.Month),
+		nullIfZeroInt(date.Day),
+		nullIfEmpty(date.Precision),
+	}
+}
 
+// updatePublishedDates parses the date_published of the books whose publication date wasn't parsed yet
+func updatePublishedDates(ctx context.Context, db *sql.DB) {
+	rows, err := db.QueryContext(ctx, `SELECT id, date_published FROM books WHERE published_date_precision IS NULL AND date_published IS NOT NULL AND date_published != ''`)
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	bookId, err := result.LastInsertId()
+	var updates [][]any
+	var id int
+	var datePublished string
+	for rows.Next() {
+		err := rows.Scan(&id, &datePublished)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		if pubdate.Parse(datePublished).Precision == "" {
+			continue
+		}
+
+		updates = append(updates, append(getPublishedDateValues(datePublished), id))
+	}
+
+	err = rows.Err()
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	return int(bookId)
-}
-
-func insertVolume(ctx context.Context, db *sql.DB, volume google.Volume, publisherId int, languageId int) int {
-	var isbn10, isbn13, dimensions *string
-
-	for _, volumeInfo := range volume.VolumeInfo.IndustryIdentifiers {
-		if volumeInfo.Type == "ISBN_10" {
-			isbn10 = &volumeInfo.Identifier
-		} else if volumeInfo.Type == "ISBN_13" {
-			isbn13 = &volumeInfo.Identifier
-		}
-	}
-
-	if volume.VolumeInfo.Dimensions.Height > 0 || volume.VolumeInfo.Dimensions.Width > 0 || volume.VolumeInfo.Dimensions.Thickness > 0 {
-		dimensionsString := fmt.Sprintf("%v x %v x %v", volume.VolumeInfo.Dimensions.Height, volume.VolumeInfo.Dimensions.Width, volume.VolumeInfo.Dimensions.Thickness)
-		dimensions = &dimensionsString
-	}
-
-	result, err := db.ExecContext(ctx, `INSERT INTO books
-		(
-		 	google_id,
-			title, 
-			subtitle,
-			publisher_id,
-			date_published,
-			synopsis,
-			isbn,
-			isbn13,
-		 	pages,
-			average_rating,
-			rating_count,
-		 	language_id,
-			main_category,
-			dimensions
-		) VALUES (
-			?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?
-		)`,
-		volume.Id,
-		volume.VolumeInfo.Title,
-		volume.VolumeInfo.Subtitle,
-		publisherId,
-		volume.VolumeInfo.PublishedDate,
-		fmt.Sprintf("%.*s", 10000, volume.VolumeInfo.Description),
-		isbn10,
-		isbn13,
-		volume.VolumeInfo.PageCount,
-		volume.VolumeInfo.AverageRating,
-		volume.VolumeInfo.RatingsCount,
-		languageId,
-		volume.VolumeInfo.MainCategory,
-		dimensions,
-	)
+	err = rows.Close()
+	if err != nil {
+		log.Fatal(err)
+	}
 
+	tx, err := db.BeginTx(ctx, nil)
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	volumeId, err := result.LastInsertId()
+	assignments := strings.Join(publishedDateColumns, " = ?, ") + " = ?"
+	for _, update := range updates {
+		_, err := tx.ExecContext(ctx, rebind(`UPDATE books SET `+assignments+` WHERE id = ?`), update...)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	err = tx.Commit()
 	if err != nil {
 		log.Fatal(err)
 	}
+}
+
+// insertBooks returns the ids of the inserted books by the value of their id column. A book that shares an identifier
+// with a saved book is skipped and has no id, the dedupe utility merges the books that were saved before identifiers
+// were unique.
+func insertBooks(ctx context.Context, tx *sql.Tx, idColumn string, books []provider.Record, ids lookupIds, savedAt time.Time) map[string]int {
+	if len(books) == 0 {
+		return nil
+	}
+
+	rows := make([][]any, 0, len(books))
+	bookIds := make([]string, 0, len(books))
+	for _, book := range books {
+		row := append(getBookValues(book, ids), getPublishedDateValues(book.DatePublished)...)
+		rows = append(rows, append(row, savedAt))
+		bookIds = append(bookIds, book.Id)
+	}
+
+	columns := slices.Concat(bookColumns, publishedDateColumns)
+	insertRows(ctx, tx, `INSERT INTO books (`+strings.Join(columns, ", ")+`, updated_at) VALUES `, rows, onConflictIgnore("id"))
+
+	// the inserted ids are selected because mysql only returns the first one and the order of returned ids is not
+	// guaranteed by the other databases
+	return selectIds(ctx, tx, "books", idColumn, bookIds)
+}
+
+func insertAuthorBooks(ctx context.Context, tx *sql.Tx, books []provider.Record, bookIds map[string]int, ids lookupIds) {
+	var rows [][]any
+	for _, book := range books {
+		bookId, isInserted := bookIds[book.Id]
+		if !isInserted {
+			continue
+		}
+
+		for _, author := range book.Authors {
+			authorId, isSaved := ids["authors"][authorname.Key(author)]
+			if isSaved {
+				rows = append(rows, []any{authorId, bookId})
+			}
+		}
+	}
 
-	return int(volumeId)
+	insertRows(ctx, tx, `INSERT INTO author_book (author_id, book_id) VALUES `, rows, onConflictIgnore("author_id"))
+}
+
+func insertBookSubjects(ctx context.Context, tx *sql.Tx, books []provider.Record, bookIds map[string]int, ids lookupIds) {
+	var rows [][]any
+	for _, book := range books {
+		bookId, isInserted := bookIds[book.Id]
+		if !isInserted {
+			continue
+		}
+
+		for _, subject := range book.Subjects {
+			rows = append(rows, []any{bookId, ids["subjects"][subject]})
+		}
+	}
+
+	insertRows(ctx, tx, `INSERT INTO book_subject (book_id, subject_id) VALUES `, rows, onConflictIgnore("book_id"))
+}
+
+func insertIndustryIdentifiers(ctx context.Context, tx *sql.Tx, books []provider.Record, bookIds map[string]int) {
+	var rows [][]any
+	for _, book := range books {
+		bookId, isInserted := bookIds[book.Id]
+		if !isInserted {
+			continue
+		}
+
+		for _, industryIdentifier := range book.IndustryIdentifiers {
+			rows = append(rows, []any{industryIdentifier.Type, industryIdentifier.Identifier, bookId})
+		}
+	}
+
+	insertRows(ctx, tx, `INSERT INTO industry_identifiers (type, identifier, book_id) VALUES `, rows, ``)
+}
+
+func insertOtherIsbns(ctx context.Context, tx *sql.Tx, books []provider.Record, bookIds map[string]int) {
+	var rows [][]any
+	for _, book := range books {
+		bookId, isInserted := bookIds[book.Id]
+		if !isInserted {
+			continue
+		}
+
+		for _, otherIsbn := range book.OtherIsbns {
+			rows = append(rows, []any{bookId, otherIsbn.Isbn, otherIsbn.Binding})
+		}
+	}
+
+	insertRows(ctx, tx, `INSERT INTO other_isbns (book_id, isbn, binding) VALUES `, rows, ``)
+}
+
+func insertReviews(ctx context.Context, tx *sql.Tx, books []provider.Record, bookIds map[string]int) {
+	var rows [][]any
+	for _, book := range books {
+		bookId, isInserted := bookIds[book.Id]
+		if !isInserted {
+			continue
+		}
+
+		for _, review := range book.Reviews {
+			rows = append(rows, []any{bookId, review})
+		}
+	}
+
+	insertRows(ctx, tx, `INSERT INTO reviews (book_id, review) VALUES `, rows, ``)
+}
+
+// insertBookDimensions only saves the books that have at least one measurement
+func insertBookDimensions(ctx context.Context, tx *sql.Tx, books []provider.Record, bookIds map[string]int) {
+	var rows [][]any
+	for _, book := range books {
+		bookId, isInserted := bookIds[book.Id]
+		if !isInserted || formatDimensions(book.DimensionsStructured) == "" {
+			continue
+		}
+
+		row := []any{bookId}
+		for _, measurement := range getMeasurements(book.DimensionsStructured) {
+			row = append(row, nullIfZero(measurement.Value), nullIfEmpty(measurement.Unit))
+		}
+		rows = append(rows, row)
+	}
+
+	insertRows(ctx, tx, `INSERT INTO book_dimensions (book_id, length, length_unit, width, width_unit, height, height_unit, weight, weight_unit) VALUES `, rows, ``)
+}
+
+func insertBookImages(ctx context.Context, tx *sql.Tx, books []provider.Record, bookIds map[string]int) {
+	var rows [][]any
+	for _, book := range books {
+		bookId, isInserted := bookIds[book.Id]
+		if !isInserted {
+			continue
+		}
+
+		for _, imageLink := range book.ImageLinks {
+			rows = append(rows, []any{bookId, imageLink.Size, imageLink.Url})
+		}
+	}
+
+	insertRows(ctx, tx, `INSERT INTO book_images (book_id, size, url) VALUES `, rows, ``)
+}
+
+func insertBookSeries(ctx context.Context, tx *sql.Tx, books []provider.Record, bookIds map[string]int) {
+	var rows [][]any
+	for _, book := range books {
+		bookId, isInserted := bookIds[book.Id]
+		if !isInserted {
+			continue
+		}
+
+		for _, series := range book.Series {
+			rows = append(rows, []any{bookId, series.SeriesId, series.BookType, series.OrderNumber})
+		}
+	}
+
+	insertRows(ctx, tx, `INSERT INTO book_series (book_id, series_id, series_book_type, order_number) VALUES `, rows, ``)
+}
+
+// insertBookAccess only saves the books that the api returned access info for
+func insertBookAccess(ctx context.Context, tx *sql.Tx, books []provider.Record, bookIds map[string]int) {
+	var rows [][]any
+	for _, book := range books {
+		bookId, isInserted := bookIds[book.Id]
+		if !isInserted || book.Access == (provider.Access{}) {
+			continue
+		}
+
+		access := book.Access
+		rows = append(rows, []any{
+			bookId,
+			nullIfEmpty(access.Country),
+			nullIfEmpty(access.Viewability),
+			nullIfEmpty(access.AccessViewStatus),
+			nullIfEmpty(access.TextToSpeechPermission),
+			nullIfEmpty(access.MaturityRating),
+			access.Embeddable,
+			access.PublicDomain,
+			access.EpubAvailable,
+			access.PdfAvailable,
+		})
+	}
+
+	insertRows(ctx, tx, `INSERT INTO book_access (
+		book_id, country, viewability, access_view_status, text_to_speech_permission, maturity_rating, embeddable, public_domain, epub_available, pdf_available
+	) VALUES `, rows, ``)
+}
+
+// insertPrices saves the prices of every search as a new snapshot, even if they didn't change, so that the history shows
+// when each price was seen
+func insertPrices(ctx context.Context, tx *sql.Tx, books []provider.Record, bookIds map[string]int, savedAt time.Time) {
+	var rows [][]any
+	for _, book := range books {
+		bookId, isInserted := bookIds[book.Id]
+		if !isInserted {
+			continue
+		}
+
+		for _, price := range book.Prices {
+			rows = append(rows, []any{
+				bookId,
+				nullIfEmpty(price.Merchant),
+				nullIfEmpty(price.Country),
+				nullIfEmpty(price.Condition),
+				nullIfEmpty(price.Currency),
+				price.ListPrice,
+				price.Price,
+				price.Shipping,
+				price.Total,
+				nullIfEmpty(price.Link),
+				savedAt,
+			})
+		}
+	}
+
+	insertRows(ctx, tx, `INSERT INTO book_prices (book_id, merchant, country, item_condition, currency, list_price, price, shipping, total, link, collected_at) VALUES `, rows, ``)
 }
 
 func insertQuery(ctx context.Context, db *sql.DB, query string) {
-	_, err := db.ExecContext(ctx, `INSERT INTO searched_queries (query) VALUES (?)`, query)
+	_, err := db.ExecContext(ctx, rebind(`INSERT INTO searched_queries (query) VALUES (?)`), query)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func saveQueryProgress(ctx context.Context, db *sql.DB, query string, progress QueryProgress) {
+	deleteQueryProgress(ctx, db, query)
+
+	_, err := db.ExecContext(ctx, rebind(`INSERT INTO query_progress (query, total, last_page) VALUES (?, ?, ?)`), query, progress.Total, progress.LastPage)
 	if err != nil {
 		log.Fatal(err)
 	}
 }
+
+// saveSearchedIsbns replaces the previous status of the isbns in one transaction
+func saveSearchedIsbns(ctx context.Context, db *sql.DB, isbns []string, status string) {
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(isbns)), ", ")
+	args := make([]any, 0, len(isbns))
+	for _, searchedIsbn := range isbns {
+		args = append(args, searchedIsbn)
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	_, err = tx.ExecContext(ctx, rebind(`DELETE FROM searched_isbns WHERE isbn IN (`+placeholders+`)`), args...)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	searchedAt := time.Now().UTC()
+	values := strings.TrimSuffix(strings.Repeat("(?, ?, ?), ", len(isbns)), ", ")
+	args = make([]any, 0, len(isbns)*3)
+	for _, searchedIsbn := range isbns {
+		args = append(args, searchedIsbn, status, searchedAt)
+	}
+
+	_, err = tx.ExecContext(ctx, rebind(`INSERT INTO searched_isbns (isbn, status, searched_at) VALUES `+values), args...)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func deleteQueryProgress(ctx context.Context, db *sql.DB, query string) {
+	_, err := db.ExecContext(ctx, rebind(`DELETE FROM query_progress WHERE query = ?`), query)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// normalizeRecord trims the values that are compared with saved values and cuts them to the length of their columns
+func normalizeRecord(record provider.Record) provider.Record {
+	record.Id = fmt.Sprintf("%.*s", 500, strings.TrimSpace(record.Id))
+	record.Isbn13 = fmt.Sprintf("%.*s", 500, strings.TrimSpace(record.Isbn13))
+	record.Synopsis = fmt.Sprintf("%.*s", 10000, record.Synopsis)
+	record.Publisher = normalizeName(record.Publisher)
+	record.Language = normalizeName(record.Language)
+
+	authors := make([]string, 0, len(record.Authors))
+	for _, author := range record.Authors {
+		authors = append(authors, normalizeName(author))
+	}
+	record.Authors = authors
+
+	subjects := make([]string, 0, len(record.Subjects))
+	for _, subject := range record.Subjects {
+		subjects = append(subjects, normalizeName(subject))
+	}
+	record.Subjects = subjects
+
+	return record
+}
+
+func normalizeName(name string) string {
+	return fmt.Sprintf("%.*s", 500, strings.TrimSpace(name))
+}
+
+// insertRows inserts rows that have the same number of values with as few statements as the limit of placeholders per
+// statement allows. The suffix is added after the values of each statement.
+func insertRows(ctx context.Context, tx *sql.Tx, insert string, rows [][]any, suffix string) {
+	if len(rows) == 0 {
+		return
+	}
+
+	rowPlaceholders := "(" + strings.TrimSuffix(strings.Repeat("?, ", len(rows[0])), ", ") + ")"
+	for chunk := range slices.Chunk(rows, maxPlaceholders/len(rows[0])) {
+		values := strings.TrimSuffix(strings.Repeat(rowPlaceholders+", ", len(chunk)), ", ")
+		args := make([]any, 0, len(chunk)*len(rows[0]))
+		for _, row := range chunk {
+			args = append(args, row...)
+		}
+
+		_, err := tx.ExecContext(ctx, rebind(insert+values+suffix), args...)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+}
+
+// selectIds returns the ids of the rows that have the values in the column by value
+func selectIds(ctx context.Context, tx *sql.Tx, tableName string, columnName string, values []string) map[string]int {
+	args := make([]any, 0, len(values))
+	for _, value := range values {
+		args = append(args, value)
+	}
+
+	ids := make(map[string]int)
+	var id int
+	var value string
+
+	queryIn(ctx, tx, `SELECT id, `+columnName+` FROM `+tableName+` WHERE `+columnName+` IN (%s)`, args, func(rows *sql.Rows) {
+		err := rows.Scan(&id, &value)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		ids[value] = id
+	})
+
+	return ids
+}
+
+// queryIn runs the query for chunks of the values that fit in the limit of placeholders per statement and scans each
+// row. The query has a %s where the placeholders of the values go.
+func queryIn(ctx context.Context, tx *sql.Tx, query string, values []any, scan func(rows *sql.Rows)) {
+	for chunk := range slices.Chunk(values, maxPlaceholders) {
+		placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(chunk)), ", ")
+
+		rows, err := tx.QueryContext(ctx, rebind(fmt.Sprintf(query, placeholders)), chunk...)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		for rows.Next() {
+			scan(rows)
+		}
+
+		err = rows.Err()
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		err = rows.Close()
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+}
+
+func nullIfZero(value float64) *float64 {
+	if value == 0 {
+		return nil
+	}
+
+	return &value
+}
+
+func nullIfZeroInt(value int) *int {
+	if value == 0 {
+		return nil
+	}
+
+	return &value
+}
+
+// nullIfEmpty is used for identifier columns so that missing identifiers are saved as NULL instead of empty strings
+func nullIfEmpty(value string) *string {
+	if value == "" {
+		return nil
+	}
+
+	return &value
+}
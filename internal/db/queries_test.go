@@ -0,0 +1,207 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/zaelmyth/book-data-collector/internal/provider"
+)
+
+// testPage is a page of records that a test saves. The saved data is loaded again before a reloaded page is saved, the
+// way it is when the collector is started again, and a concurrent page is saved with the saved data that was loaded
+// before any page was saved, the way another collector that runs at the same time would save it.
+type testPage struct {
+	records      []provider.Record
+	isRefresh    bool
+	isReloaded   bool
+	isConcurrent bool
+}
+
+var hobbit = provider.Record{
+	Id:            "9780261103344",
+	Isbn13:        "9780261103344",
+	Isbn:          "0261103342",
+	Title:         "The Hobbit",
+	Publisher:     "HarperCollins",
+	Language:      "English",
+	DatePublished: "1995-05-02",
+	Authors:       []string{"Tolkien, J.R.R."},
+	Subjects:      []string{"Fiction", "Fantasy"},
+	IndustryIdentifiers: []provider.IndustryIdentifier{
+		{Type: "ISBN_13", Identifier: "9780261103344"},
+		{Type: "ISBN_10", Identifier: "0261103342"},
+	},
+}
+
+var silmarillion = provider.Record{
+	Id:        "9780261102736",
+	Isbn13:    "9780261102736",
+	Title:     "The Silmarillion",
+	Publisher: "HarperCollins",
+	Language:  "eng",
+	Authors:   []string{"J. R. R. Tolkien", "Christopher Tolkien"},
+	Subjects:  []string{"Fiction"},
+}
+
+func TestSaveBooks(t *testing.T) {
+	renamedHobbit := hobbit
+	renamedHobbit.Title = "The Hobbit, or There and Back Again"
+
+	withoutId := silmarillion
+	withoutId.Id = ""
+
+	tests := []struct {
+		name   string
+		pages  []testPage
+		counts map[string]int
+	}{
+		{
+			name:  "new books",
+			pages: []testPage{{records: []provider.Record{hobbit, silmarillion}}},
+			counts: map[string]int{
+				`books`: 2,
+				`books WHERE published_year = 1995 AND published_date_precision = 'day'`: 1,
+				`publishers`:       1,
+				`languages`:        1,
+				`language_aliases`: 2,
+				`authors`:          2,
+				`authors WHERE name = 'J. R. R. Tolkien'`: 1,
+				`author_aliases`:       3,
+				`author_book`:          3,
+				`subjects`:             2,
+				`book_subject`:         3,
+				`industry_identifiers`: 2,
+				`book_changes`:         0,
+			},
+		},
+		{
+			name:   "books without an id are skipped",
+			pages:  []testPage{{records: []provider.Record{withoutId}}},
+			counts: map[string]int{`books`: 0, `authors`: 0},
+		},
+		{
+			name: "saved books are skipped",
+			pages: []testPage{
+				{records: []provider.Record{hobbit}},
+				{records: []provider.Record{renamedHobbit, silmarillion}},
+			},
+			counts: map[string]int{
+				`books`:                            2,
+				`books WHERE title = 'The Hobbit'`: 1,
+				`author_book`:                      3,
+				`book_changes`:                     0,
+			},
+		},
+		{
+			name: "saved books are skipped after a restart",
+			pages: []testPage{
+				{records: []provider.Record{hobbit}},
+				{records: []provider.Record{hobbit, silmarillion}, isReloaded: true},
+			},
+			counts: map[string]int{`books`: 2, `author_book`: 3, `industry_identifiers`: 2},
+		},
+		{
+			name: "refreshed books are updated",
+			pages: []testPage{
+				{records: []provider.Record{hobbit}},
+				{records: []provider.Record{renamedHobbit}, isRefresh: true},
+			},
+			counts: map[string]int{
+				`books`: 1,
+				`books WHERE title = 'The Hobbit, or There and Back Again'`: 1,
+				`book_changes WHERE field = 'title'`:                        1,
+				`book_changes`:                                              1,
+				`author_book`:                                               1,
+			},
+		},
+		{
+			name: "refreshed books that didn't change are not updated",
+			pages: []testPage{
+				{records: []provider.Record{hobbit}},
+				{records: []provider.Record{hobbit}, isRefresh: true, isReloaded: true},
+			},
+			counts: map[string]int{`books`: 1, `book_changes`: 0},
+		},
+		{
+			name: "lookups saved by a concurrent collector",
+			pages: []testPage{
+				{records: []provider.Record{hobbit}},
+				{records: []provider.Record{silmarillion}, isConcurrent: true},
+			},
+			counts: map[string]int{
+				`books`:          2,
+				`publishers`:     1,
+				`languages`:      1,
+				`authors`:        2,
+				`subjects`:       2,
+				`author_aliases`: 3,
+				`author_book`:    3,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			forEachDriver(t, func(t *testing.T, databases testDatabases) {
+				ctx := context.Background()
+				MigrateUp(ctx, databases.booksDb, databases.progressDb)
+
+				savedData := newTestSavedData(ctx, databases, "isbn13")
+				concurrentSavedData := newTestSavedData(ctx, databases, "isbn13")
+				for _, page := range test.pages {
+					if page.isReloaded {
+						savedData = newTestSavedData(ctx, databases, "isbn13")
+					}
+
+					if page.isConcurrent {
+						SaveBooks(ctx, databases.booksDb, "isbn13", page.records, concurrentSavedData, page.isRefresh)
+					} else {
+						SaveBooks(ctx, databases.booksDb, "isbn13", page.records, savedData, page.isRefresh)
+					}
+				}
+
+				for query, want := range test.counts {
+					count := countRows(t, databases.booksDb, query)
+					if count != want {
+						t.Errorf("%v: %v rows, want %v", query, count, want)
+					}
+				}
+			})
+		})
+	}
+}
+
+func TestGetBookIds(t *testing.T) {
+	forEachDriver(t, func(t *testing.T, databases testDatabases) {
+		ctx := context.Background()
+		MigrateUp(ctx, databases.booksDb, databases.progressDb)
+
+		withoutId := provider.Record{Id: "OL1M", OpenLibraryId: "OL1M", Title: "Unfinished Tales"}
+		SaveBooks(ctx, databases.booksDb, "open_library_id", []provider.Record{withoutId}, newTestSavedData(ctx, databases, "open_library_id"), false)
+		SaveBooks(ctx, databases.booksDb, "isbn13", []provider.Record{hobbit, silmarillion}, newTestSavedData(ctx, databases, "isbn13"), false)
+
+		if count := CountBookIds(ctx, databases.booksDb, "isbn13"); count != 2 {
+			t.Errorf("counted %v ids, want 2", count)
+		}
+
+		var ids []string
+		lastId := 0
+		for {
+			var page []string
+			page, lastId = GetBookIds(ctx, databases.booksDb, "isbn13", lastId, 1)
+			if len(page) == 0 {
+				break
+			}
+
+			if len(page) > 1 {
+				t.Fatalf("got %v ids, want at most 1", len(page))
+			}
+
+			ids = append(ids, page...)
+		}
+
+		if len(ids) != 2 || ids[0] != hobbit.Isbn13 || ids[1] != silmarillion.Isbn13 {
+			t.Errorf("ids = %q, want the isbn-13 of the books with one in the order they were saved", ids)
+		}
+	})
+}
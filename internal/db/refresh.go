@@ -0,0 +1,521 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/zaelmyth/book-data-collector/internal/authorname"
+	"github.com/zaelmyth/book-data-collector/internal/provider"
+)
+
+// savedBook is a book as it is saved in the database with its values formatted the same way as getChangedValues
+type savedBook struct {
+	id     int
+	values map[string]string
+}
+
+type bookChange struct {
+	bookId   int
+	field    string
+	oldValue string
+	newValue string
+}
+
+// updateBooks updates the columns and relations of the books that changed since they were saved and keeps the values
+// before and after each change in the book_changes table. It returns the ids of all the saved books by the value of
+// their id column.
+func updateBooks(ctx context.Context, tx *sql.Tx, idColumn string, books []provider.Record, ids lookupIds, savedAt time.Time) map[string]int {
+	if len(books) == 0 {
+		return nil
+	}
+
+	savedBooks := selectSavedBooks(ctx, tx, idColumn, books)
+
+	changedBooks := make(map[string]map[string]string)
+	for _, book := range books {
+		saved, isSaved := savedBooks[book.Id]
+		if isSaved {
+			changedBooks[book.Id] = getChangedValues(book, saved.values)
+		}
+	}
+
+	identifierOwners := selectIdentifierOwners(ctx, tx, changedBooks)
+
+	var changes []bookChange
+	var authorBooks, subjectBooks, industryIdentifierBooks, otherIsbnBooks, reviewBooks, dimensionBooks []provider.Record
+	var imageBooks, seriesBooks, accessBooks []provider.Record
+	bookIds := make(map[string]int)
+
+	for _, book := range books {
+		saved, isSaved := savedBooks[book.Id]
+		if !isSaved {
+			continue
+		}
+
+		bookIds[book.Id] = saved.id
+
+		changedValues := changedBooks[book.Id]
+		if len(changedValues) == 0 {
+			continue
+		}
+		bookValues := getBookValues(book, ids)
+		var columns []string
+		var args []any
+
+		for field, value := range changedValues {
+			// the identifier columns are unique so a book keeps its identifier if another book already has the new one
+			if slices.Contains(identifierColumns, field) && value != "" {
+				ownerId, isOwned := identifierOwners[field][value]
+				if isOwned && ownerId != saved.id {
+					log.Printf("Book %v keeps its %v because book %v already has %v\n", saved.id, field, ownerId, value)
+					continue
+				}
+
+				identifierOwners[field][value] = saved.id
+			}
+
+			changes = append(changes, bookChange{bookId: saved.id, field: field, oldValue: saved.values[field], newValue: value})
+
+			switch field {
+			case "publisher":
+				columns = append(columns, "publisher_id")
+				args = append(args, ids["publishers"][book.Publisher])
+			case "language":
+				columns = append(columns, "language_id")
+				args = append(args, nullIfZeroInt(ids["languages"][languageName(book.Language)]))
+			case "authors":
+				authorBooks = append(authorBooks, book)
+			case "subjects":
+				subjectBooks = append(subjectBooks, book)
+			case "industry_identifiers":
+				industryIdentifierBooks = append(industryIdentifierBooks, book)
+			case "other_isbns":
+				otherIsbnBooks = append(otherIsbnBooks, book)
+			case "reviews":
+				reviewBooks = append(reviewBooks, book)
+			case "book_dimensions":
+				dimensionBooks = append(dimensionBooks, book)
+			case "book_images":
+				imageBooks = append(imageBooks, book)
+			case "book_series":
+				seriesBooks = append(seriesBooks, book)
+			case "book_access":
+				accessBooks = append(accessBooks, book)
+			case "date_published":
+				columns = append(columns, "date_published")
+				args = append(args, book.DatePublished)
+				columns = append(columns, publishedDateColumns...)
+				args = append(args, getPublishedDateValues(book.DatePublished)...)
+			default:
+				columns = append(columns, field)
+				args = append(args, bookValues[slices.Index(bookColumns, field)])
+			}
+		}
+
+		assignments := ""
+		for _, column := range columns {
+			assignments += column + ` = ?, `
+		}
+
+		_, err := tx.ExecContext(ctx, rebind(`UPDATE books SET `+assignments+`updated_at = ? WHERE id = ?`), append(args, savedAt, saved.id)...)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	deleteBookRelations(ctx, tx, "author_book", authorBooks, bookIds)
+	insertAuthorBooks(ctx, tx, authorBooks, bookIds, ids)
+
+	deleteBookRelations(ctx, tx, "book_subject", subjectBooks, bookIds)
+	insertBookSubjects(ctx, tx, subjectBooks, bookIds, ids)
+
+	deleteBookRelations(ctx, tx, "industry_identifiers", industryIdentifierBooks, bookIds)
+	insertIndustryIdentifiers(ctx, tx, industryIdentifierBooks, bookIds)
+
+	deleteBookRelations(ctx, tx, "other_isbns", otherIsbnBooks, bookIds)
+	insertOtherIsbns(ctx, tx, otherIsbnBooks, bookIds)
+
+	deleteBookRelations(ctx, tx, "reviews", reviewBooks, bookIds)
+	insertReviews(ctx, tx, reviewBooks, bookIds)
+
+	deleteBookRelations(ctx, tx, "book_dimensions", dimensionBooks, bookIds)
+	insertBookDimensions(ctx, tx, dimensionBooks, bookIds)
+
+	deleteBookRelations(ctx, tx, "book_images", imageBooks, bookIds)
+	insertBookImages(ctx, tx, imageBooks, bookIds)
+
+	deleteBookRelations(ctx, tx, "book_series", seriesBooks, bookIds)
+	insertBookSeries(ctx, tx, seriesBooks, bookIds)
+
+	deleteBookRelations(ctx, tx, "book_access", accessBooks, bookIds)
+	insertBookAccess(ctx, tx, accessBooks, bookIds)
+
+	rows := make([][]any, 0, len(changes))
+	for _, change := range changes {
+		rows = append(rows, []any{change.bookId, change.field, change.oldValue, change.newValue, savedAt})
+	}
+
+	insertRows(ctx, tx, `INSERT INTO book_changes (book_id, field, old_value, new_value, changed_at) VALUES `, rows, ``)
+
+	return bookIds
+}
+
+// selectIdentifierOwners returns the ids of the saved books that have the changed identifiers by column and identifier
+func selectIdentifierOwners(ctx context.Context, tx *sql.Tx, changedBooks map[string]map[string]string) map[string]map[string]int {
+	owners := make(map[string]map[string]int)
+	for _, column := range identifierColumns {
+		owners[column] = make(map[string]int)
+
+		var identifiers []any
+		for _, changedValues := range changedBooks {
+			if changedValues[column] != "" {
+				identifiers = append(identifiers, changedValues[column])
+			}
+		}
+
+		var bookId int
+		var identifier string
+		queryIn(ctx, tx, `SELECT id, `+column+` FROM books WHERE `+column+` IN (%s)`, identifiers, func(rows *sql.Rows) {
+			err := rows.Scan(&bookId, &identifier)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			owners[column][identifier] = bookId
+		})
+	}
+
+	return owners
+}
+
+// getChangedValues returns the new values of the fields that are different from the saved values by field name. The
+// publisher, language and relations are compared by their names instead of their ids, and the authors by the keys of
+// their names.
+func getChangedValues(book provider.Record, savedValues map[string]string) map[string]string {
+	values := map[string]string{
+		"publisher":            book.Publisher,
+		"language":             languageName(book.Language),
+		"authors":              joinSorted(book.Authors),
+		"subjects":             joinSorted(book.Subjects),
+		"industry_identifiers": joinSorted(getIndustryIdentifierValues(book.IndustryIdentifiers)),
+		"other_isbns":          joinSorted(getOtherIsbnValues(book.OtherIsbns)),
+		"reviews":              joinSorted(book.Reviews),
+		"book_dimensions":      formatDimensions(book.DimensionsStructured),
+		"book_images":          joinSorted(getImageLinkValues(book.ImageLinks)),
+		"book_series":          joinSorted(getSeriesValues(book.Series)),
+		"book_access":          formatAccess(book.Access),
+	}
+
+	for i, value := range getBookValues(book, nil) {
+		if bookColumns[i] == "publisher_id" || bookColumns[i] == "language_id" {
+			continue
+		}
+
+		values[bookColumns[i]] = formatValue(value)
+	}
+
+	changedValues := make(map[string]string)
+	for field, value := range values {
+		if field == "average_rating" && isSameFloat(value, savedValues[field]) {
+			continue
+		}
+
+		// authors are the same if they are only written differently
+		if field == "authors" && joinSorted(getAuthorKeys(book.Authors)) == savedValues["author_keys"] {
+			continue
+		}
+
+		if value != savedValues[field] {
+			changedValues[field] = value
+		}
+	}
+
+	return changedValues
+}
+
+// selectSavedBooks returns the saved books by the value of their id column
+func selectSavedBooks(ctx context.Context, tx *sql.Tx, idColumn string, books []provider.Record) map[string]savedBook {
+	columns := make([]string, 0, len(bookColumns))
+	for _, column := range bookColumns {
+		if column != "publisher_id" && column != "language_id" {
+			columns = append(columns, column)
+		}
+	}
+
+	args := make([]any, 0, len(books))
+	for _, book := range books {
+		args = append(args, book.Id)
+	}
+
+	savedBooks := make(map[string]savedBook)
+	var bookId int
+	var id string
+	values := make([]sql.NullString, len(columns)+2)
+	destinations := []any{&bookId, &id}
+	for i := range values {
+		destinations = append(destinations, &values[i])
+	}
+
+	query := `SELECT books.id, books.` + idColumn + `, books.` + strings.Join(columns, ", books.") + `, publishers.name, languages.name
+		FROM books
+		LEFT JOIN publishers ON publishers.id = books.publisher_id
+		LEFT JOIN languages ON languages.id = books.language_id
+		WHERE books.` + idColumn + ` IN (%s)`
+	queryIn(ctx, tx, query, args, func(rows *sql.Rows) {
+		err := rows.Scan(destinations...)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		book := savedBook{id: bookId, values: make(map[string]string)}
+		for i, column := range columns {
+			book.values[column] = values[i].String
+		}
+		book.values["publisher"] = values[len(columns)].String
+		book.values["language"] = values[len(columns)+1].String
+
+		savedBooks[id] = book
+	})
+
+	bookIdArgs := make([]any, 0, len(savedBooks))
+	for _, book := range savedBooks {
+		bookIdArgs = append(bookIdArgs, book.id)
+	}
+
+	relations := map[string]string{
+		"authors":              `SELECT author_book.book_id, authors.name FROM author_book JOIN authors ON authors.id = author_book.author_id WHERE author_book.book_id IN (%s)`,
+		"author_keys":          `SELECT author_book.book_id, authors.name_key FROM author_book JOIN authors ON authors.id = author_book.author_id WHERE author_book.book_id IN (%s)`,
+		"subjects":             `SELECT book_subject.book_id, subjects.name FROM book_subject JOIN subjects ON subjects.id = book_subject.subject_id WHERE book_subject.book_id IN (%s)`,
+		"industry_identifiers": `SELECT book_id, ` + concatColumns("type", "identifier") + ` FROM industry_identifiers WHERE book_id IN (%s)`,
+		"other_isbns":          `SELECT book_id, ` + concatColumns("isbn", "binding") + ` FROM other_isbns WHERE book_id IN (%s)`,
+		"book_images":          `SELECT book_id, ` + concatColumns("size", "url") + ` FROM book_images WHERE book_id IN (%s)`,
+		"book_series":          `SELECT book_id, ` + concatColumns("series_id", "series_book_type", "order_number") + ` FROM book_series WHERE book_id IN (%s)`,
+		"reviews":              `SELECT book_id, review FROM reviews WHERE book_id IN (%s)`,
+	}
+
+	for field, query := range relations {
+		names := make(map[int][]string)
+		var name string
+
+		queryIn(ctx, tx, query, bookIdArgs, func(rows *sql.Rows) {
+			err := rows.Scan(&bookId, &name)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			names[bookId] = append(names[bookId], name)
+		})
+
+		for _, book := range savedBooks {
+			book.values[field] = joinSorted(names[book.id])
+		}
+	}
+
+	savedDimensions := selectSavedDimensions(ctx, tx, bookIdArgs)
+	savedAccess := selectSavedAccess(ctx, tx, bookIdArgs)
+	for _, book := range savedBooks {
+		book.values["book_dimensions"] = formatDimensions(savedDimensions[book.id])
+		book.values["book_access"] = formatAccess(savedAccess[book.id])
+	}
+
+	return savedBooks
+}
+
+func selectSavedDimensions(ctx context.Context, tx *sql.Tx, bookIds []any) map[int]provider.Dimensions {
+	savedDimensions := make(map[int]provider.Dimensions)
+	var bookId int
+	values := make([]sql.NullFloat64, 4)
+	units := make([]sql.NullString, 4)
+
+	query := `SELECT book_id, length, length_unit, width, width_unit, height, height_unit, weight, weight_unit FROM book_dimensions WHERE book_id IN (%s)`
+	queryIn(ctx, tx, query, bookIds, func(rows *sql.Rows) {
+		err := rows.Scan(&bookId, &values[0], &units[0], &values[1], &units[1], &values[2], &units[2], &values[3], &units[3])
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		savedDimensions[bookId] = provider.Dimensions{
+			Length: provider.Measurement{Unit: units[0].String, Value: values[0].Float64},
+			Width:  provider.Measurement{Unit: units[1].String, Value: values[1].Float64},
+			Height: provider.Measurement{Unit: units[2].String, Value: values[2].Float64},
+			Weight: provider.Measurement{Unit: units[3].String, Value: values[3].Float64},
+		}
+	})
+
+	return savedDimensions
+}
+
+func selectSavedAccess(ctx context.Context, tx *sql.Tx, bookIds []any) map[int]provider.Access {
+	savedAccess := make(map[int]provider.Access)
+	var bookId int
+	var country, viewability, accessViewStatus, textToSpeechPermission, maturityRating sql.NullString
+	var embeddable, publicDomain, epubAvailable, pdfAvailable sql.NullBool
+
+	query := `SELECT book_id, country, viewability, access_view_status, text_to_speech_permission, maturity_rating, embeddable, public_domain, epub_available, pdf_available
+		FROM book_access WHERE book_id IN (%s)`
+	queryIn(ctx, tx, query, bookIds, func(rows *sql.Rows) {
+		err := rows.Scan(&bookId, &country, &viewability, &accessViewStatus, &textToSpeechPermission, &maturityRating, &embeddable, &publicDomain, &epubAvailable, &pdfAvailable)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		savedAccess[bookId] = provider.Access{
+			Country:                country.String,
+			Viewability:            viewability.String,
+			AccessViewStatus:       accessViewStatus.String,
+			TextToSpeechPermission: textToSpeechPermission.String,
+			MaturityRating:         maturityRating.String,
+			Embeddable:             embeddable.Bool,
+			PublicDomain:           publicDomain.Bool,
+			EpubAvailable:          epubAvailable.Bool,
+			PdfAvailable:           pdfAvailable.Bool,
+		}
+	})
+
+	return savedAccess
+}
+
+func deleteBookRelations(ctx context.Context, tx *sql.Tx, tableName string, books []provider.Record, bookIds map[string]int) {
+	for _, book := range books {
+		_, err := tx.ExecContext(ctx, rebind(`DELETE FROM `+tableName+` WHERE book_id = ?`), bookIds[book.Id])
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+}
+
+func getIndustryIdentifierValues(industryIdentifiers []provider.IndustryIdentifier) []string {
+	values := make([]string, 0, len(industryIdentifiers))
+	for _, industryIdentifier := range industryIdentifiers {
+		values = append(values, industryIdentifier.Type+":"+industryIdentifier.Identifier)
+	}
+
+	return values
+}
+
+func getOtherIsbnValues(otherIsbns []provider.OtherIsbn) []string {
+	values := make([]string, 0, len(otherIsbns))
+	for _, otherIsbn := range otherIsbns {
+		values = append(values, otherIsbn.Isbn+":"+otherIsbn.Binding)
+	}
+
+	return values
+}
+
+func getImageLinkValues(imageLinks []provider.ImageLink) []string {
+	values := make([]string, 0, len(imageLinks))
+	for _, imageLink := range imageLinks {
+		values = append(values, imageLink.Size+":"+imageLink.Url)
+	}
+
+	return values
+}
+
+func getSeriesValues(series []provider.Series) []string {
+	values := make([]string, 0, len(series))
+	for _, oneSeries := range series {
+		values = append(values, oneSeries.SeriesId+":"+oneSeries.BookType+":"+strconv.Itoa(oneSeries.OrderNumber))
+	}
+
+	return values
+}
+
+// concatColumns formats saved columns the same way as the get...Values functions, separated by colons
+func concatColumns(columns ...string) string {
+	if driver == "mysql" {
+		return `CONCAT(` + strings.Join(columns, `, ':', `) + `)`
+	}
+
+	return strings.Join(columns, ` || ':' || `)
+}
+
+// getMeasurements returns the measurements in the order of the book_dimensions columns
+func getMeasurements(dimensions provider.Dimensions) []provider.Measurement {
+	return []provider.Measurement{dimensions.Length, dimensions.Width, dimensions.Height, dimensions.Weight}
+}
+
+// formatAccess is empty if the book has no access info
+func formatAccess(access provider.Access) string {
+	if access == (provider.Access{}) {
+		return ""
+	}
+
+	return fmt.Sprintf("%+v", access)
+}
+
+// formatDimensions is empty if the book has no measurements
+func formatDimensions(dimensions provider.Dimensions) string {
+	names := []string{"length", "width", "height", "weight"}
+	var values []string
+	for i, measurement := range getMeasurements(dimensions) {
+		if measurement.Value == 0 {
+			continue
+		}
+
+		values = append(values, names[i]+": "+strconv.FormatFloat(measurement.Value, 'g', -1, 64)+" "+measurement.Unit)
+	}
+
+	return strings.Join(values, "\n")
+}
+
+// getAuthorKeys returns the keys of the authors once each, the same way they are linked to a book
+func getAuthorKeys(authors []string) []string {
+	var keys []string
+	for _, author := range authors {
+		key := authorname.Key(author)
+		if key != "" && !slices.Contains(keys, key) {
+			keys = append(keys, key)
+		}
+	}
+
+	return keys
+}
+
+// joinSorted formats the values of a relation as a set, so that relations that have the same values in another order or
+// more than once are the same
+func joinSorted(values []string) string {
+	return strings.Join(slices.Compact(slices.Sorted(slices.Values(values))), "\n")
+}
+
+// formatValue formats the values of getBookValues the same way the database drivers format them as strings
+func formatValue(value any) string {
+	switch value := value.(type) {
+	case *string:
+		if value == nil {
+			return ""
+		}
+		return *value
+	case string:
+		return value
+	case int:
+		return strconv.Itoa(value)
+	case float64:
+		return strconv.FormatFloat(value, 'g', -1, 64)
+	default:
+		log.Fatal("Invalid book value type")
+		return ""
+	}
+}
+
+// isSameFloat compares floats with the precision of a mysql FLOAT column, which is saved with less precision than the
+// values of the api
+func isSameFloat(value string, savedValue string) bool {
+	float, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return false
+	}
+
+	savedFloat, err := strconv.ParseFloat(savedValue, 64)
+	if err != nil {
+		return false
+	}
+
+	return math.Abs(float-savedFloat) <= 1e-6*max(1, math.Abs(float))
+}
@@ -0,0 +1,88 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/zaelmyth/book-data-collector/internal/provider"
+)
+
+func TestRefreshBooks(t *testing.T) {
+	hobbitWithGoogleId := hobbit
+	hobbitWithGoogleId.GoogleId = "pD6arNyKyi8C"
+
+	silmarillionWithGoogleId := silmarillion
+	silmarillionWithGoogleId.GoogleId = "ODVvAwAAQBAJ"
+
+	reordered := hobbit
+	reordered.Subjects = []string{"Fantasy", "Fiction", "Fantasy"}
+	reordered.Authors = []string{"J.R.R. Tolkien", "Tolkien, J. R. R."}
+	reordered.IndustryIdentifiers = []provider.IndustryIdentifier{hobbit.IndustryIdentifiers[1], hobbit.IndustryIdentifiers[0], hobbit.IndustryIdentifiers[1]}
+
+	withTakenGoogleId := silmarillionWithGoogleId
+	withTakenGoogleId.GoogleId = hobbitWithGoogleId.GoogleId
+	withTakenGoogleId.Title = "The Silmarillion (Illustrated)"
+
+	withNewSubject := hobbit
+	withNewSubject.Subjects = []string{"Fiction", "Fantasy", "Dragons"}
+
+	tests := []struct {
+		name    string
+		saved   []provider.Record
+		refresh []provider.Record
+		counts  map[string]int
+	}{
+		{
+			name:    "relations in another order or repeated didn't change",
+			saved:   []provider.Record{hobbit},
+			refresh: []provider.Record{reordered},
+			counts:  map[string]int{`book_changes`: 0, `book_subject`: 2, `author_book`: 1, `industry_identifiers`: 2},
+		},
+		{
+			name:    "relations that changed are replaced",
+			saved:   []provider.Record{hobbit},
+			refresh: []provider.Record{withNewSubject},
+			counts:  map[string]int{`book_changes WHERE field = 'subjects'`: 1, `book_subject`: 3},
+		},
+		{
+			name:    "identifiers of other books are not taken",
+			saved:   []provider.Record{hobbitWithGoogleId, silmarillionWithGoogleId},
+			refresh: []provider.Record{withTakenGoogleId},
+			counts: map[string]int{
+				`book_changes`:                                         1,
+				`book_changes WHERE field = 'title'`:                   1,
+				`books WHERE google_id = 'ODVvAwAAQBAJ'`:               1,
+				`books WHERE title = 'The Silmarillion (Illustrated)'`: 1,
+			},
+		},
+		{
+			name:    "identifiers are taken once in a page",
+			saved:   []provider.Record{hobbit, silmarillion},
+			refresh: []provider.Record{hobbitWithGoogleId, withTakenGoogleId},
+			counts: map[string]int{
+				`book_changes WHERE field = 'google_id'`: 1,
+				`books WHERE google_id = 'pD6arNyKyi8C'`: 1,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			forEachDriver(t, func(t *testing.T, databases testDatabases) {
+				ctx := context.Background()
+				MigrateUp(ctx, databases.booksDb, databases.progressDb)
+
+				savedData := newTestSavedData(ctx, databases, "isbn13")
+				SaveBooks(ctx, databases.booksDb, "isbn13", test.saved, savedData, false)
+				SaveBooks(ctx, databases.booksDb, "isbn13", test.refresh, savedData, true)
+
+				for query, want := range test.counts {
+					count := countRows(t, databases.booksDb, query)
+					if count != want {
+						t.Errorf("%v: %v rows, want %v", query, count, want)
+					}
+				}
+			})
+		})
+	}
+}
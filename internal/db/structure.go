@@ -3,12 +3,24 @@ package db
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"log"
+	"slices"
+	"strings"
 
+	"github.com/go-sql-driver/mysql"
 	"github.com/zaelmyth/book-data-collector/internal/configuration"
 )
 
+// unknownDatabaseErrorNumber is the mysql error for a connection to a database that doesn't exist
+const unknownDatabaseErrorNumber = 1049
+
 func CreateDatabases(ctx context.Context, config configuration.Config) {
+	// sqlite creates the file when it is opened
+	if config.DbDriver == "sqlite" {
+		return
+	}
+
 	db := getDatabase(config)
 	defer func(db *sql.DB) {
 		err := db.Close()
@@ -17,6 +29,11 @@ func CreateDatabases(ctx context.Context, config configuration.Config) {
 		}
 	}(db)
 
+	if config.DbDriver == "postgres" {
+		createSchemas(ctx, db, config)
+		return
+	}
+
 	_, err := db.ExecContext(ctx, `CREATE DATABASE IF NOT EXISTS `+config.DbNameBooks+` DEFAULT CHARACTER SET = 'utf8mb4' DEFAULT COLLATE 'utf8mb4_bin';`)
 	if err != nil {
 		log.Fatal(err)
@@ -29,37 +46,67 @@ func CreateDatabases(ctx context.Context, config configuration.Config) {
 }
 
 func GetBooksDatabase(config configuration.Config) *sql.DB {
-	mysqlConnectionString := getMysqlConnectionString(config)
-	// the database has to be declared in the connection instead of with a "USE" statement because of concurrency issues
-	db, err := sql.Open("mysql", mysqlConnectionString+config.DbNameBooks+"?charset=utf8mb4")
+	return openDatabase(config, config.DbNameBooks)
+}
+
+func GetProgressDatabase(config configuration.Config) *sql.DB {
+	return openDatabase(config, config.DbNameProgress)
+}
+
+func createProgressTables(ctx context.Context, progressDb *sql.DB) {
+	_, err := progressDb.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS searched_queries (query TEXT);`)
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	return db
-}
+	_, err = progressDb.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS query_progress (query TEXT, total INTEGER, last_page INTEGER);`)
+	if err != nil {
+		log.Fatal(err)
+	}
 
-func GetProgressDatabase(config configuration.Config) *sql.DB {
-	mysqlConnectionString := getMysqlConnectionString(config)
-	// the database has to be declared in the connection instead of with a "USE" statement because of concurrency issues
-	db, err := sql.Open("mysql", mysqlConnectionString+config.DbNameProgress+"?charset=utf8mb4")
+	_, err = progressDb.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS searched_isbns (isbn VARCHAR(20) PRIMARY KEY, status VARCHAR(20), searched_at `+timestamp()+`);`)
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	return db
+	_, err = progressDb.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS api_calls (provider VARCHAR(100), day VARCHAR(10), calls INTEGER, PRIMARY KEY (provider, day));`)
+	if err != nil {
+		log.Fatal(err)
+	}
 }
 
-func CreateProgressTables(ctx context.Context, progressDb *sql.DB) {
-	_, err := progressDb.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS searched_queries (query TEXT);`)
+func dropProgressTables(ctx context.Context, progressDb *sql.DB) {
+	dropTables(ctx, progressDb, "searched_queries", "query_progress", "searched_isbns", "api_calls")
+}
+
+func createApiResponsesTable(ctx context.Context, progressDb *sql.DB) {
+	_, err := progressDb.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS api_responses (
+		id `+primaryKey()+`,
+		provider VARCHAR(100),
+		endpoint VARCHAR(500),
+		parameters TEXT,
+		body `+blob()+`,
+		created_at `+timestamp()+`
+	);`)
 	if err != nil {
 		log.Fatal(err)
 	}
+
+	if !hasIndex(ctx, progressDb, "api_responses", "api_responses_endpoint_index") {
+		_, err := progressDb.ExecContext(ctx, `CREATE INDEX api_responses_endpoint_index ON api_responses (provider, endpoint);`)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
 }
 
-func CreateBookTables(ctx context.Context, db *sql.DB) {
+func dropApiResponsesTable(ctx context.Context, progressDb *sql.DB) {
+	dropTables(ctx, progressDb, "api_responses")
+}
+
+func createBookTables(ctx context.Context, db *sql.DB) {
 	_, err := db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS books (
-		id INTEGER PRIMARY KEY AUTO_INCREMENT,
+		id `+primaryKey()+`,
 		title TEXT,
 		title_long TEXT,
 		isbn TEXT NULL,
@@ -83,23 +130,22 @@ func CreateBookTables(ctx context.Context, db *sql.DB) {
 		average_rating FLOAT,
 		rating_count INTEGER,
 		main_category TEXT
-# 		UNIQUE (isbn13)
 	);`)
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	_, err = db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS publishers (id INTEGER PRIMARY KEY AUTO_INCREMENT, name VARCHAR(500), UNIQUE (name));`)
+	_, err = db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS publishers (id `+primaryKey()+`, name VARCHAR(500), UNIQUE (name));`)
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	_, err = db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS languages (id INTEGER PRIMARY KEY AUTO_INCREMENT, name VARCHAR(500), UNIQUE (name));`)
+	_, err = db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS languages (id `+primaryKey()+`, name VARCHAR(500), UNIQUE (name));`)
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	_, err = db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS authors (id INTEGER PRIMARY KEY AUTO_INCREMENT, name VARCHAR(500), UNIQUE (name));`)
+	_, err = db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS authors (id `+primaryKey()+`, name VARCHAR(500), UNIQUE (name));`)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -109,7 +155,7 @@ func CreateBookTables(ctx context.Context, db *sql.DB) {
 		log.Fatal(err)
 	}
 
-	_, err = db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS subjects (id INTEGER PRIMARY KEY AUTO_INCREMENT, name VARCHAR(500), UNIQUE (name));`)
+	_, err = db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS subjects (id `+primaryKey()+`, name VARCHAR(500), UNIQUE (name));`)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -119,34 +165,478 @@ func CreateBookTables(ctx context.Context, db *sql.DB) {
 		log.Fatal(err)
 	}
 
-	_, err = db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS industry_identifiers (id INTEGER PRIMARY KEY AUTO_INCREMENT, type TEXT, identifier TEXT, book_id INTEGER);`)
+	_, err = db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS industry_identifiers (id `+primaryKey()+`, type TEXT, identifier TEXT, book_id INTEGER);`)
 	if err != nil {
 		log.Fatal(err)
 	}
 }
 
-func CreateOpenLibraryIdColumn(ctx context.Context, db *sql.DB) {
+func dropBookTables(ctx context.Context, db *sql.DB) {
+	dropTables(ctx, db, "books", "publishers", "languages", "authors", "author_book", "subjects", "book_subject", "industry_identifiers")
+}
+
+func createOpenLibraryIdColumn(ctx context.Context, db *sql.DB) {
+	// books tables created by older versions already have the column
+	if hasColumn(ctx, db, "books", "open_library_id") {
+		return
+	}
+
 	_, err := db.ExecContext(ctx, `ALTER TABLE books ADD open_library_id TEXT;`)
 	if err != nil {
 		log.Fatal(err)
 	}
 }
 
-func CreateOpenLibraryRatingsTable(ctx context.Context, db *sql.DB) {
-	_, err := db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS open_library_ratings (id INTEGER PRIMARY KEY AUTO_INCREMENT, rating FLOAT, date DATE, book_id INTEGER);`)
+func dropOpenLibraryIdColumn(ctx context.Context, db *sql.DB) {
+	dropColumn(ctx, db, "books", "open_library_id")
+}
+
+func createBooksUpdatedAtColumn(ctx context.Context, db *sql.DB) {
+	if hasColumn(ctx, db, "books", "updated_at") {
+		return
+	}
+
+	_, err := db.ExecContext(ctx, `ALTER TABLE books ADD updated_at `+timestamp()+` NULL;`)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func dropBooksUpdatedAtColumn(ctx context.Context, db *sql.DB) {
+	dropColumn(ctx, db, "books", "updated_at")
+}
+
+func createBookChangesTable(ctx context.Context, db *sql.DB) {
+	_, err := db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS book_changes (id `+primaryKey()+`, book_id INTEGER, field VARCHAR(100), old_value TEXT, new_value TEXT, changed_at `+timestamp()+`);`)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func dropBookChangesTable(ctx context.Context, db *sql.DB) {
+	dropTables(ctx, db, "book_changes")
+}
+
+func createBooksUniqueKeys(ctx context.Context, db *sql.DB) {
+	// mysql can only index a prefix of TEXT columns
+	if driver == "mysql" {
+		_, err := db.ExecContext(ctx, `ALTER TABLE books MODIFY google_id VARCHAR(500) NULL, MODIFY open_library_id VARCHAR(500) NULL;`)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	for _, column := range identifierColumns {
+		if hasIndex(ctx, db, "books", "books_"+column+"_unique") {
+			continue
+		}
+
+		_, err := db.ExecContext(ctx, `CREATE UNIQUE INDEX books_`+column+`_unique ON books (`+column+`);`)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+}
+
+func dropBooksUniqueKeys(ctx context.Context, db *sql.DB) {
+	for _, column := range identifierColumns {
+		if !hasIndex(ctx, db, "books", "books_"+column+"_unique") {
+			continue
+		}
+
+		query := `DROP INDEX books_` + column + `_unique;`
+		if driver == "mysql" {
+			query = `DROP INDEX books_` + column + `_unique ON books;`
+		}
+
+		_, err := db.ExecContext(ctx, query)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if driver == "mysql" {
+		_, err := db.ExecContext(ctx, `ALTER TABLE books MODIFY google_id TEXT NULL, MODIFY open_library_id TEXT NULL;`)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+}
+
+// linkKeys are the primary keys and foreign keys of the tables that link books to other tables
+var linkKeys = []struct {
+	tableName   string
+	primaryKey  []string
+	foreignKeys map[string]string
+}{
+	{"author_book", []string{"author_id", "book_id"}, map[string]string{"author_id": "authors", "book_id": "books"}},
+	{"book_subject", []string{"book_id", "subject_id"}, map[string]string{"book_id": "books", "subject_id": "subjects"}},
+	{"industry_identifiers", nil, map[string]string{"book_id": "books"}},
+}
+
+func createLinkKeys(ctx context.Context, db *sql.DB) {
+	// sqlite can't add keys to existing tables
+	if driver == "sqlite" {
+		rebuildTable(ctx, db, "author_book", `author_id INTEGER NOT NULL REFERENCES authors (id), book_id INTEGER NOT NULL REFERENCES books (id), PRIMARY KEY (author_id, book_id)`, "author_id, book_id")
+		rebuildTable(ctx, db, "book_subject", `book_id INTEGER NOT NULL REFERENCES books (id), subject_id INTEGER NOT NULL REFERENCES subjects (id), PRIMARY KEY (book_id, subject_id)`, "book_id, subject_id")
+		rebuildTable(ctx, db, "industry_identifiers", `id `+primaryKey()+`, type TEXT, identifier TEXT, book_id INTEGER REFERENCES books (id)`, "id, type, identifier, book_id")
+		return
+	}
+
+	for _, keys := range linkKeys {
+		if keys.primaryKey != nil && !hasConstraint(ctx, db, keys.tableName, primaryKeyName(keys.tableName)) {
+			_, err := db.ExecContext(ctx, `ALTER TABLE `+keys.tableName+` ADD CONSTRAINT `+keys.tableName+`_pkey PRIMARY KEY (`+strings.Join(keys.primaryKey, ", ")+`);`)
+			if err != nil {
+				log.Fatal(err)
+			}
+		}
+
+		for column, referencedTable := range keys.foreignKeys {
+			if hasConstraint(ctx, db, keys.tableName, keys.tableName+"_"+column+"_fk") {
+				continue
+			}
+
+			_, err := db.ExecContext(ctx, `ALTER TABLE `+keys.tableName+` ADD CONSTRAINT `+keys.tableName+`_`+column+`_fk FOREIGN KEY (`+column+`) REFERENCES `+referencedTable+` (id);`)
+			if err != nil {
+				log.Fatal(err)
+			}
+		}
+	}
+}
+
+func dropLinkKeys(ctx context.Context, db *sql.DB) {
+	if driver == "sqlite" {
+		rebuildTable(ctx, db, "author_book", `author_id INTEGER, book_id INTEGER`, "author_id, book_id")
+		rebuildTable(ctx, db, "book_subject", `book_id INTEGER, subject_id INTEGER`, "book_id, subject_id")
+		rebuildTable(ctx, db, "industry_identifiers", `id `+primaryKey()+`, type TEXT, identifier TEXT, book_id INTEGER`, "id, type, identifier, book_id")
+		return
+	}
+
+	dropForeignKey := `DROP CONSTRAINT `
+	if driver == "mysql" {
+		dropForeignKey = `DROP FOREIGN KEY `
+	}
+
+	for _, keys := range linkKeys {
+		for column := range keys.foreignKeys {
+			if !hasConstraint(ctx, db, keys.tableName, keys.tableName+"_"+column+"_fk") {
+				continue
+			}
+
+			_, err := db.ExecContext(ctx, `ALTER TABLE `+keys.tableName+` `+dropForeignKey+keys.tableName+`_`+column+`_fk;`)
+			if err != nil {
+				log.Fatal(err)
+			}
+		}
+
+		if keys.primaryKey != nil && hasConstraint(ctx, db, keys.tableName, primaryKeyName(keys.tableName)) {
+			// mysql primary keys can only be dropped with their own syntax
+			dropPrimaryKey := `DROP CONSTRAINT ` + primaryKeyName(keys.tableName)
+			if driver == "mysql" {
+				dropPrimaryKey = `DROP PRIMARY KEY`
+			}
+
+			_, err := db.ExecContext(ctx, `ALTER TABLE `+keys.tableName+` `+dropPrimaryKey+`;`)
+			if err != nil {
+				log.Fatal(err)
+			}
+		}
+	}
+}
+
+// createBookPricesTable names the column item_condition because condition is a reserved word in mysql
+func createBookPricesTable(ctx context.Context, db *sql.DB) {
+	_, err := db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS book_prices (
+		id `+primaryKey()+`,
+		book_id INTEGER,
+		merchant VARCHAR(255),
+		country VARCHAR(10),
+		item_condition VARCHAR(100),
+		currency VARCHAR(10),
+		list_price DECIMAL(12, 2),
+		price DECIMAL(12, 2),
+		shipping DECIMAL(12, 2),
+		total DECIMAL(12, 2),
+		link TEXT,
+		collected_at `+timestamp()+`
+	);`)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if !hasIndex(ctx, db, "book_prices", "book_prices_book_id_index") {
+		_, err := db.ExecContext(ctx, `CREATE INDEX book_prices_book_id_index ON book_prices (book_id, collected_at);`)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+}
+
+func dropBookPricesTable(ctx context.Context, db *sql.DB) {
+	dropTables(ctx, db, "book_prices")
+}
+
+func createOtherIsbnsTable(ctx context.Context, db *sql.DB) {
+	_, err := db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS other_isbns (id `+primaryKey()+`, book_id INTEGER, isbn VARCHAR(20), binding VARCHAR(255));`)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// the formats of a title are found by the isbns they share
+	if !hasIndex(ctx, db, "other_isbns", "other_isbns_isbn_index") {
+		_, err := db.ExecContext(ctx, `CREATE INDEX other_isbns_isbn_index ON other_isbns (isbn);`)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+}
+
+func dropOtherIsbnsTable(ctx context.Context, db *sql.DB) {
+	dropTables(ctx, db, "other_isbns")
+}
+
+func createReviewsTable(ctx context.Context, db *sql.DB) {
+	_, err := db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS reviews (id `+primaryKey()+`, book_id INTEGER, review TEXT);`)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func dropReviewsTable(ctx context.Context, db *sql.DB) {
+	dropTables(ctx, db, "reviews")
+}
+
+// createBookDimensionsTable uses DOUBLE PRECISION because mysql FLOAT values would change on every refresh
+func createBookDimensionsTable(ctx context.Context, db *sql.DB) {
+	_, err := db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS book_dimensions (
+		book_id INTEGER PRIMARY KEY,
+		length DOUBLE PRECISION,
+		length_unit VARCHAR(20),
+		width DOUBLE PRECISION,
+		width_unit VARCHAR(20),
+		height DOUBLE PRECISION,
+		height_unit VARCHAR(20),
+		weight DOUBLE PRECISION,
+		weight_unit VARCHAR(20)
+	);`)
 	if err != nil {
 		log.Fatal(err)
 	}
 }
 
-func CreateOpenLibraryReadingLogsTable(ctx context.Context, db *sql.DB) {
-	_, err := db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS open_library_reading_logs (id INTEGER PRIMARY KEY AUTO_INCREMENT, status TEXT, date DATE, book_id INTEGER);`)
+func dropBookDimensionsTable(ctx context.Context, db *sql.DB) {
+	dropTables(ctx, db, "book_dimensions")
+}
+
+func createBookImagesTable(ctx context.Context, db *sql.DB) {
+	_, err := db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS book_images (id `+primaryKey()+`, book_id INTEGER, size VARCHAR(20), url TEXT);`)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func dropBookImagesTable(ctx context.Context, db *sql.DB) {
+	dropTables(ctx, db, "book_images")
+}
+
+func createBookSeriesTable(ctx context.Context, db *sql.DB) {
+	_, err := db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS book_series (id `+primaryKey()+`, book_id INTEGER, series_id VARCHAR(255), series_book_type VARCHAR(100), order_number INTEGER);`)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// the books of a series are listed in order by the series id
+	if !hasIndex(ctx, db, "book_series", "book_series_series_id_index") {
+		_, err := db.ExecContext(ctx, `CREATE INDEX book_series_series_id_index ON book_series (series_id, order_number);`)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+}
+
+func dropBookSeriesTable(ctx context.Context, db *sql.DB) {
+	dropTables(ctx, db, "book_series")
+}
+
+func createBookAccessTable(ctx context.Context, db *sql.DB) {
+	_, err := db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS book_access (
+		book_id INTEGER PRIMARY KEY,
+		country VARCHAR(10),
+		viewability VARCHAR(100),
+		access_view_status VARCHAR(100),
+		text_to_speech_permission VARCHAR(100),
+		maturity_rating VARCHAR(100),
+		embeddable BOOLEAN,
+		public_domain BOOLEAN,
+		epub_available BOOLEAN,
+		pdf_available BOOLEAN
+	);`)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func dropBookAccessTable(ctx context.Context, db *sql.DB) {
+	dropTables(ctx, db, "book_access")
+}
+
+func createPublishedDateColumns(ctx context.Context, db *sql.DB) {
+	definitions := map[string]string{
+		"published_year":           "INTEGER NULL",
+		"published_month":          "INTEGER NULL",
+		"published_day":            "INTEGER NULL",
+		"published_date_precision": "VARCHAR(10) NULL",
+	}
+
+	for _, column := range publishedDateColumns {
+		if hasColumn(ctx, db, "books", column) {
+			continue
+		}
+
+		_, err := db.ExecContext(ctx, `ALTER TABLE books ADD `+column+` `+definitions[column]+`;`)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if !hasIndex(ctx, db, "books", "books_published_year_index") {
+		_, err := db.ExecContext(ctx, `CREATE INDEX books_published_year_index ON books (published_year, published_month, published_day);`)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	updatePublishedDates(ctx, db)
+}
+
+func dropPublishedDateColumns(ctx context.Context, db *sql.DB) {
+	// sqlite can't drop indexed columns
+	if hasIndex(ctx, db, "books", "books_published_year_index") {
+		query := `DROP INDEX books_published_year_index;`
+		if driver == "mysql" {
+			query = `DROP INDEX books_published_year_index ON books;`
+		}
+
+		_, err := db.ExecContext(ctx, query)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	for _, column := range publishedDateColumns {
+		dropColumn(ctx, db, "books", column)
+	}
+}
+
+func createLanguageAliasesTable(ctx context.Context, db *sql.DB) {
+	_, err := db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS language_aliases (id `+primaryKey()+`, alias VARCHAR(500), language_id INTEGER, UNIQUE (alias));`)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func dropLanguageAliasesTable(ctx context.Context, db *sql.DB) {
+	dropTables(ctx, db, "language_aliases")
+}
+
+func createAuthorsNameKeyColumn(ctx context.Context, db *sql.DB) {
+	if hasColumn(ctx, db, "authors", "name_key") {
+		return
+	}
+
+	_, err := db.ExecContext(ctx, `ALTER TABLE authors ADD name_key VARCHAR(500) NULL;`)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func dropAuthorsNameKeyColumn(ctx context.Context, db *sql.DB) {
+	dropColumn(ctx, db, "authors", "name_key")
+}
+
+func createAuthorAliasesTable(ctx context.Context, db *sql.DB) {
+	_, err := db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS author_aliases (id `+primaryKey()+`, alias VARCHAR(500), author_id INTEGER, UNIQUE (alias));`)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func dropAuthorAliasesTable(ctx context.Context, db *sql.DB) {
+	dropTables(ctx, db, "author_aliases")
+}
+
+// createAuthorsNameKeyUniqueKey runs after the authors are normalized, authors with the same key would violate it
+func createAuthorsNameKeyUniqueKey(ctx context.Context, db *sql.DB) {
+	if hasIndex(ctx, db, "authors", "authors_name_key_unique") {
+		return
+	}
+
+	_, err := db.ExecContext(ctx, `CREATE UNIQUE INDEX authors_name_key_unique ON authors (name_key);`)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func dropAuthorsNameKeyUniqueKey(ctx context.Context, db *sql.DB) {
+	if !hasIndex(ctx, db, "authors", "authors_name_key_unique") {
+		return
+	}
+
+	query := `DROP INDEX authors_name_key_unique;`
+	if driver == "mysql" {
+		query = `DROP INDEX authors_name_key_unique ON authors;`
+	}
+
+	_, err := db.ExecContext(ctx, query)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func createOpenLibraryRatingsTable(ctx context.Context, db *sql.DB) {
+	_, err := db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS open_library_ratings (id `+primaryKey()+`, rating FLOAT, date DATE, book_id INTEGER);`)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func dropOpenLibraryRatingsTable(ctx context.Context, db *sql.DB) {
+	dropTables(ctx, db, "open_library_ratings")
+}
+
+func createOpenLibraryReadingLogsTable(ctx context.Context, db *sql.DB) {
+	_, err := db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS open_library_reading_logs (id `+primaryKey()+`, status TEXT, date DATE, book_id INTEGER);`)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func dropOpenLibraryReadingLogsTable(ctx context.Context, db *sql.DB) {
+	dropTables(ctx, db, "open_library_reading_logs")
+}
+
+func createSchemas(ctx context.Context, db *sql.DB, config configuration.Config) {
+	_, err := db.ExecContext(ctx, `CREATE SCHEMA IF NOT EXISTS `+config.DbNameBooks+`;`)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	_, err = db.ExecContext(ctx, `CREATE SCHEMA IF NOT EXISTS `+config.DbNameProgress+`;`)
 	if err != nil {
 		log.Fatal(err)
 	}
 }
 
 func getDatabase(config configuration.Config) *sql.DB {
+	if config.DbDriver == "postgres" {
+		db, err := sql.Open("postgres", getPostgresConnectionString(config, ""))
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		return db
+	}
+
 	mysqlConnectionString := getMysqlConnectionString(config)
 	db, err := sql.Open("mysql", mysqlConnectionString)
 	if err != nil {
@@ -159,3 +649,158 @@ func getDatabase(config configuration.Config) *sql.DB {
 func getMysqlConnectionString(config configuration.Config) string {
 	return config.DbUsername + ":" + config.DbPassword + "@tcp(" + config.DbHost + ":" + config.DbPort + ")/"
 }
+
+// hasSchema is false if the database, or the postgres schema, that the connection uses was not created yet
+func hasSchema(ctx context.Context, db *sql.DB) bool {
+	if driver == "sqlite" {
+		return true
+	}
+
+	if driver == "postgres" {
+		// the current schema is null if the schema in the search path doesn't exist
+		var isCreated bool
+		err := db.QueryRowContext(ctx, `SELECT current_schema() IS NOT NULL`).Scan(&isCreated)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		return isCreated
+	}
+
+	var mysqlError *mysql.MySQLError
+	err := db.PingContext(ctx)
+	if errors.As(err, &mysqlError) && mysqlError.Number == unknownDatabaseErrorNumber {
+		return false
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	return true
+}
+
+func hasColumn(ctx context.Context, db *sql.DB, tableName string, columnName string) bool {
+	rows, err := db.QueryContext(ctx, `SELECT * FROM `+tableName+` WHERE 1 = 0`)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			log.Fatal(err)
+		}
+	}(rows)
+
+	columns, err := rows.Columns()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	return slices.Contains(columns, columnName)
+}
+
+// rebuildTable copies the table into a new one with the definition because sqlite can't alter existing tables
+func rebuildTable(ctx context.Context, db *sql.DB, tableName string, definition string, columns string) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	queries := []string{
+		`DROP TABLE IF EXISTS ` + tableName + `_rebuilt;`,
+		`CREATE TABLE ` + tableName + `_rebuilt (` + definition + `);`,
+		`INSERT INTO ` + tableName + `_rebuilt (` + columns + `) SELECT ` + columns + ` FROM ` + tableName + `;`,
+		`DROP TABLE ` + tableName + `;`,
+		`ALTER TABLE ` + tableName + `_rebuilt RENAME TO ` + tableName + `;`,
+	}
+
+	for _, query := range queries {
+		_, err := tx.ExecContext(ctx, query)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func hasTable(ctx context.Context, db *sql.DB, tableName string) bool {
+	query := `SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = DATABASE() AND table_name = ?`
+	if driver == "postgres" {
+		query = `SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = current_schema() AND table_name = ?`
+	} else if driver == "sqlite" {
+		query = `SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?`
+	}
+
+	var count int
+	err := db.QueryRowContext(ctx, rebind(query), tableName).Scan(&count)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	return count > 0
+}
+
+func hasIndex(ctx context.Context, db *sql.DB, tableName string, indexName string) bool {
+	query := `SELECT COUNT(*) FROM information_schema.statistics WHERE table_schema = DATABASE() AND table_name = ? AND index_name = ?`
+	if driver == "postgres" {
+		query = `SELECT COUNT(*) FROM pg_indexes WHERE schemaname = current_schema() AND tablename = ? AND indexname = ?`
+	} else if driver == "sqlite" {
+		query = `SELECT COUNT(*) FROM sqlite_master WHERE type = 'index' AND tbl_name = ? AND name = ?`
+	}
+
+	var count int
+	err := db.QueryRowContext(ctx, rebind(query), tableName, indexName).Scan(&count)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	return count > 0
+}
+
+func hasConstraint(ctx context.Context, db *sql.DB, tableName string, constraintName string) bool {
+	schema := `DATABASE()`
+	if driver == "postgres" {
+		schema = `current_schema()`
+	}
+
+	var count int
+	err := db.QueryRowContext(ctx, rebind(`SELECT COUNT(*) FROM information_schema.table_constraints WHERE table_schema = `+schema+` AND table_name = ? AND constraint_name = ?`), tableName, constraintName).Scan(&count)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	return count > 0
+}
+
+// primaryKeyName is the name of the primary key constraint, mysql always names it PRIMARY
+func primaryKeyName(tableName string) string {
+	if driver == "mysql" {
+		return "PRIMARY"
+	}
+
+	return tableName + "_pkey"
+}
+
+func dropTables(ctx context.Context, db *sql.DB, tableNames ...string) {
+	for _, tableName := range tableNames {
+		_, err := db.ExecContext(ctx, `DROP TABLE IF EXISTS `+tableName+`;`)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+}
+
+func dropColumn(ctx context.Context, db *sql.DB, tableName string, columnName string) {
+	if !hasColumn(ctx, db, tableName, columnName) {
+		return
+	}
+
+	_, err := db.ExecContext(ctx, `ALTER TABLE `+tableName+` DROP COLUMN `+columnName+`;`)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
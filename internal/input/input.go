@@ -0,0 +1,177 @@
+package input
+
+import (
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+const stdin = "-"
+
+// Input reads all the files matched by a glob pattern one after the other as a single stream. Files ending in .gz or
+// .zst are decompressed and "-" reads from stdin.
+//
+// Progress is measured in bytes read from the files before decompression because the number of lines is not known
+// without reading everything twice.
+type Input struct {
+	paths      []string
+	next       int
+	totalBytes int64
+	readBytes  atomic.Int64
+	file       io.ReadCloser
+	reader     io.Reader
+	closeFunc  func() error
+	// needsNewLine is set at the end of every file
+	needsNewLine bool
+}
+
+func Open(pattern string) (*Input, error) {
+	if pattern == stdin {
+		return &Input{paths: []string{stdin}}, nil
+	}
+
+	paths, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(paths) == 0 {
+		return nil, errors.New("no files match " + pattern)
+	}
+
+	input := &Input{paths: paths}
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+
+		input.totalBytes += info.Size()
+	}
+
+	return input, nil
+}
+
+func (input *Input) Read(buffer []byte) (int, error) {
+	for {
+		// files are separated by a new line so the last line of a file is never joined with the first line of the next
+		if input.needsNewLine && len(buffer) > 0 {
+			input.needsNewLine = false
+			buffer[0] = '\n'
+			return 1, nil
+		}
+
+		if input.reader == nil {
+			if input.next == len(input.paths) {
+				return 0, io.EOF
+			}
+
+			err := input.openNext()
+			if err != nil {
+				return 0, err
+			}
+		}
+
+		n, err := input.reader.Read(buffer)
+		if errors.Is(err, io.EOF) {
+			err = input.closeCurrent()
+			input.needsNewLine = true
+		}
+
+		if n > 0 || err != nil {
+			return n, err
+		}
+	}
+}
+
+func (input *Input) Close() error {
+	if input.reader == nil {
+		return nil
+	}
+
+	return input.closeCurrent()
+}
+
+// ReadBytes is how many bytes were read from the files so far
+func (input *Input) ReadBytes() int64 {
+	return input.readBytes.Load()
+}
+
+// TotalBytes is the size of all the files, it is 0 when reading from stdin
+func (input *Input) TotalBytes() int64 {
+	return input.totalBytes
+}
+
+func (input *Input) openNext() error {
+	path := input.paths[input.next]
+	input.next++
+
+	var file io.ReadCloser = os.Stdin
+	if path != stdin {
+		var err error
+		file, err = os.Open(path)
+		if err != nil {
+			return err
+		}
+	}
+
+	input.file = file
+	counted := &countingReader{reader: file, count: &input.readBytes}
+	input.closeFunc = file.Close
+
+	switch filepath.Ext(path) {
+	case ".gz":
+		gzipReader, err := gzip.NewReader(counted)
+		if err != nil {
+			return err
+		}
+
+		input.reader = gzipReader
+		input.closeFunc = func() error {
+			return errors.Join(gzipReader.Close(), file.Close())
+		}
+	case ".zst":
+		zstdReader, err := zstd.NewReader(counted)
+		if err != nil {
+			return err
+		}
+
+		input.reader = zstdReader
+		input.closeFunc = func() error {
+			zstdReader.Close()
+			return file.Close()
+		}
+	default:
+		input.reader = counted
+	}
+
+	return nil
+}
+
+func (input *Input) closeCurrent() error {
+	input.reader = nil
+
+	// stdin is left open because it belongs to the process
+	if input.file == os.Stdin {
+		return nil
+	}
+
+	return input.closeFunc()
+}
+
+type countingReader struct {
+	reader io.Reader
+	count  *atomic.Int64
+}
+
+func (reader *countingReader) Read(buffer []byte) (int, error) {
+	n, err := reader.reader.Read(buffer)
+	reader.count.Add(int64(n))
+
+	return n, err
+}
@@ -0,0 +1,156 @@
+package input
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+func writeFile(t *testing.T, path string, content []byte) {
+	t.Helper()
+
+	err := os.WriteFile(path, content, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func gzipped(t *testing.T, content string) []byte {
+	t.Helper()
+
+	var buffer bytes.Buffer
+	writer := gzip.NewWriter(&buffer)
+	_, err := writer.Write([]byte(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = writer.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return buffer.Bytes()
+}
+
+func zstded(t *testing.T, content string) []byte {
+	t.Helper()
+
+	encoder, err := zstd.NewWriter(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func(encoder *zstd.Encoder) {
+		_ = encoder.Close()
+	}(encoder)
+
+	return encoder.EncodeAll([]byte(content), nil)
+}
+
+// readLines reads the whole input and returns its non empty lines
+func readLines(t *testing.T, input *Input) []string {
+	t.Helper()
+
+	content, err := io.ReadAll(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var lines []string
+	for _, line := range strings.Split(string(content), "\n") {
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+
+	return lines
+}
+
+func TestOpenMixedFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "1.jsonl"), []byte("plain 1\nplain 2\n"))
+	writeFile(t, filepath.Join(dir, "2.jsonl.gz"), gzipped(t, "gzip 1\ngzip 2\n"))
+	writeFile(t, filepath.Join(dir, "3.jsonl.zst"), zstded(t, "zstd 1\nzstd 2\n"))
+
+	input, err := Open(filepath.Join(dir, "*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func(input *Input) {
+		_ = input.Close()
+	}(input)
+
+	got := readLines(t, input)
+	want := []string{"plain 1", "plain 2", "gzip 1", "gzip 2", "zstd 1", "zstd 2"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("lines = %q, want %q", got, want)
+	}
+}
+
+func TestOpenFilesWithoutTrailingNewLine(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "1.jsonl"), []byte("first 1\nfirst 2"))
+	writeFile(t, filepath.Join(dir, "2.jsonl.gz"), gzipped(t, "second 1"))
+	writeFile(t, filepath.Join(dir, "3.jsonl"), []byte("third 1"))
+
+	input, err := Open(filepath.Join(dir, "*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func(input *Input) {
+		_ = input.Close()
+	}(input)
+
+	got := readLines(t, input)
+	want := []string{"first 1", "first 2", "second 1", "third 1"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("lines = %q, want the last line of every file on its own line %q", got, want)
+	}
+}
+
+func TestOpenWithoutMatches(t *testing.T) {
+	_, err := Open(filepath.Join(t.TempDir(), "*.jsonl"))
+	if err == nil {
+		t.Error("opened a pattern without matches")
+	}
+}
+
+func TestReadBytes(t *testing.T) {
+	dir := t.TempDir()
+	plain := []byte("plain 1\n")
+	compressed := gzipped(t, strings.Repeat("gzip line\n", 100))
+	writeFile(t, filepath.Join(dir, "1.jsonl"), plain)
+	writeFile(t, filepath.Join(dir, "2.jsonl.gz"), compressed)
+
+	input, err := Open(filepath.Join(dir, "*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func(input *Input) {
+		_ = input.Close()
+	}(input)
+
+	wantBytes := int64(len(plain) + len(compressed))
+	if input.TotalBytes() != wantBytes {
+		t.Errorf("total bytes = %d, want %d", input.TotalBytes(), wantBytes)
+	}
+	if input.ReadBytes() != 0 {
+		t.Errorf("read bytes before reading = %d, want 0", input.ReadBytes())
+	}
+
+	_, err = io.ReadAll(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the bytes are counted before decompression so they add up to the size of the files
+	if input.ReadBytes() != wantBytes {
+		t.Errorf("read bytes = %d, want %d", input.ReadBytes(), wantBytes)
+	}
+}
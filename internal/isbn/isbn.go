@@ -0,0 +1,123 @@
+package isbn
+
+import (
+	"strings"
+)
+
+// Clean removes the hyphens and spaces that isbns are often written with and upper cases the X check digit of isbn-10
+func Clean(value string) string {
+	value = strings.Map(func(char rune) rune {
+		if char == '-' || char == ' ' || char == '\t' {
+			return -1
+		}
+		return char
+	}, value)
+
+	return strings.ToUpper(value)
+}
+
+// IsValid is true for isbn-10 and isbn-13 with a correct check digit, hyphens and spaces are ignored
+func IsValid(value string) bool {
+	value = Clean(value)
+
+	switch len(value) {
+	case 10:
+		return isDigits(value[:9]) && checkDigit10(value[:9]) == value[9]
+	case 13:
+		return isDigits(value) && checkDigit13(value[:12]) == value[12]
+	default:
+		return false
+	}
+}
+
+// To13 returns the isbn-13 of a valid isbn-10 or isbn-13 and false if the value is not a valid isbn
+func To13(value string) (string, bool) {
+	if !IsValid(value) {
+		return "", false
+	}
+
+	value = Clean(value)
+	if len(value) == 13 {
+		return value, true
+	}
+
+	isbn13 := "978" + value[:9]
+
+	return isbn13 + string(checkDigit13(isbn13)), true
+}
+
+// To10 returns the isbn-10 of a valid isbn-10 or isbn-13 and false if the value is not a valid isbn or it's an isbn-13
+// that has no isbn-10, only the ones that start with 978 have one
+func To10(value string) (string, bool) {
+	if !IsValid(value) {
+		return "", false
+	}
+
+	value = Clean(value)
+	if len(value) == 10 {
+		return value, true
+	}
+
+	if !strings.HasPrefix(value, "978") {
+		return "", false
+	}
+
+	return value[3:12] + string(checkDigit10(value[3:12])), true
+}
+
+// Normalize returns the isbn-13 and isbn-10 of the first valid isbn of the values, so that a book has the same isbns
+// whichever of them the api returned. Both are empty if none of the values is valid and the isbn-10 is empty if the
+// isbn-13 doesn't have one.
+func Normalize(values ...string) (string, string) {
+	for _, value := range values {
+		isbn13, isValid := To13(value)
+		if !isValid {
+			continue
+		}
+
+		isbn10, _ := To10(isbn13)
+
+		return isbn13, isbn10
+	}
+
+	return "", ""
+}
+
+func isDigits(value string) bool {
+	for _, char := range value {
+		if char < '0' || char > '9' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// checkDigit10 weights the digits from 10 down to 2, the check digit is X when it would be 10
+func checkDigit10(digits string) byte {
+	sum := 0
+	for i, char := range digits {
+		sum += int(char-'0') * (10 - i)
+	}
+
+	checkDigit := (11 - sum%11) % 11
+	if checkDigit == 10 {
+		return 'X'
+	}
+
+	return byte('0' + checkDigit)
+}
+
+// checkDigit13 weights the digits alternately by 1 and 3
+func checkDigit13(digits string) byte {
+	sum := 0
+	for i, char := range digits {
+		weight := 1
+		if i%2 == 1 {
+			weight = 3
+		}
+		sum += int(char-'0') * weight
+	}
+
+	return byte('0' + (10-sum%10)%10)
+}
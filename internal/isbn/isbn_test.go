@@ -0,0 +1,92 @@
+package isbn
+
+import (
+	"testing"
+)
+
+func TestIsValid(t *testing.T) {
+	tests := []struct {
+		value string
+		want  bool
+	}{
+		{value: "9780261103344", want: true},
+		{value: "0261103342", want: true},
+		{value: "978-0-261-10334-4", want: true},
+		{value: "0 261 10334 2", want: true},
+		{value: "080442957X", want: true},
+		{value: "080442957x", want: true},
+		{value: "9791034304578", want: true},
+		{value: "9780261103345", want: false},
+		{value: "0261103343", want: false},
+		{value: "0804429571", want: false},
+		{value: "X804429570", want: false},
+		{value: "978026110334X", want: false},
+		{value: "978026110334", want: false},
+		{value: "", want: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.value, func(t *testing.T) {
+			got := IsValid(test.value)
+			if got != test.want {
+				t.Errorf("IsValid(%q) = %v, want %v", test.value, got, test.want)
+			}
+		})
+	}
+}
+
+func TestConvert(t *testing.T) {
+	tests := []struct {
+		value  string
+		isbn13 string
+		isbn10 string
+	}{
+		{value: "9780261103344", isbn13: "9780261103344", isbn10: "0261103342"},
+		{value: "0261103342", isbn13: "9780261103344", isbn10: "0261103342"},
+		{value: "978-0-261-10334-4", isbn13: "9780261103344", isbn10: "0261103342"},
+		{value: "0-8044-2957-x", isbn13: "9780804429573", isbn10: "080442957X"},
+		{value: "9780804429573", isbn13: "9780804429573", isbn10: "080442957X"},
+		{value: "979-10-343-0457-8", isbn13: "9791034304578", isbn10: ""},
+		{value: "9780261103345", isbn13: "", isbn10: ""},
+		{value: "not an isbn", isbn13: "", isbn10: ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.value, func(t *testing.T) {
+			isbn13, _ := To13(test.value)
+			if isbn13 != test.isbn13 {
+				t.Errorf("To13(%q) = %q, want %q", test.value, isbn13, test.isbn13)
+			}
+
+			isbn10, _ := To10(test.value)
+			if isbn10 != test.isbn10 {
+				t.Errorf("To10(%q) = %q, want %q", test.value, isbn10, test.isbn10)
+			}
+		})
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []string
+		isbn13 string
+		isbn10 string
+	}{
+		{name: "isbn-13 first", values: []string{"9780261103344", "0261103342"}, isbn13: "9780261103344", isbn10: "0261103342"},
+		{name: "only isbn-10", values: []string{"", "0261103342"}, isbn13: "9780261103344", isbn10: "0261103342"},
+		{name: "invalid isbn-13 is skipped", values: []string{"9780261103345", "0261103342"}, isbn13: "9780261103344", isbn10: "0261103342"},
+		{name: "979 prefix has no isbn-10", values: []string{"9791034304578"}, isbn13: "9791034304578", isbn10: ""},
+		{name: "none valid", values: []string{"9780261103345", "0261103343"}, isbn13: "", isbn10: ""},
+		{name: "no values", isbn13: "", isbn10: ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			isbn13, isbn10 := Normalize(test.values...)
+			if isbn13 != test.isbn13 || isbn10 != test.isbn10 {
+				t.Errorf("Normalize(%q) = %q, %q, want %q, %q", test.values, isbn13, isbn10, test.isbn13, test.isbn10)
+			}
+		})
+	}
+}
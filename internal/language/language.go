@@ -0,0 +1,360 @@
+package language
+
+import (
+	"slices"
+	"strings"
+	"unicode"
+
+	textlanguage "golang.org/x/text/language"
+)
+
+// language is a language that has an ISO 639-1 code with its ISO 639-2 codes and names. The bibliographic code is only
+// set when it's different from the terminology code.
+type language struct {
+	code              string
+	terminologyCode   string
+	bibliographicCode string
+	names             []string
+}
+
+var languages = []language{
+	{"aa", "aar", "", []string{"Afar"}},
+	{"ab", "abk", "", []string{"Abkhazian"}},
+	{"ae", "ave", "", []string{"Avestan"}},
+	{"af", "afr", "", []string{"Afrikaans"}},
+	{"ak", "aka", "", []string{"Akan"}},
+	{"am", "amh", "", []string{"Amharic"}},
+	{"an", "arg", "", []string{"Aragonese"}},
+	{"ar", "ara", "", []string{"Arabic", "العربية"}},
+	{"as", "asm", "", []string{"Assamese"}},
+	{"av", "ava", "", []string{"Avaric"}},
+	{"ay", "aym", "", []string{"Aymara"}},
+	{"az", "aze", "", []string{"Azerbaijani"}},
+	{"ba", "bak", "", []string{"Bashkir"}},
+	{"be", "bel", "", []string{"Belarusian"}},
+	{"bg", "bul", "", []string{"Bulgarian"}},
+	{"bi", "bis", "", []string{"Bislama"}},
+	{"bm", "bam", "", []string{"Bambara"}},
+	{"bn", "ben", "", []string{"Bengali", "Bangla"}},
+	{"bo", "bod", "tib", []string{"Tibetan"}},
+	{"br", "bre", "", []string{"Breton"}},
+	{"bs", "bos", "", []string{"Bosnian"}},
+	{"ca", "cat", "", []string{"Catalan", "Català"}},
+	{"ce", "che", "", []string{"Chechen"}},
+	{"ch", "cha", "", []string{"Chamorro"}},
+	{"co", "cos", "", []string{"Corsican"}},
+	{"cr", "cre", "", []string{"Cree"}},
+	{"cs", "ces", "cze", []string{"Czech", "Čeština"}},
+	{"cu", "chu", "", []string{"Church Slavic", "Old Church Slavonic"}},
+	{"cv", "chv", "", []string{"Chuvash"}},
+	{"cy", "cym", "wel", []string{"Welsh"}},
+	{"da", "dan", "", []string{"Danish", "Dansk"}},
+	{"de", "deu", "ger", []string{"German", "Deutsch"}},
+	{"dv", "div", "", []string{"Divehi", "Dhivehi"}},
+	{"dz", "dzo", "", []string{"Dzongkha"}},
+	{"ee", "ewe", "", []string{"Ewe"}},
+	{"el", "ell", "gre", []string{"Greek", "Modern Greek", "Ελληνικά"}},
+	{"en", "eng", "", []string{"English"}},
+	{"eo", "epo", "", []string{"Esperanto"}},
+	{"es", "spa", "", []string{"Spanish", "Español", "Castilian", "Castellano"}},
+	{"et", "est", "", []string{"Estonian"}},
+	{"eu", "eus", "baq", []string{"Basque", "Euskara"}},
+	{"fa", "fas", "per", []string{"Persian", "Farsi"}},
+	{"ff", "ful", "", []string{"Fulah"}},
+	{"fi", "fin", "", []string{"Finnish", "Suomi"}},
+	{"fj", "fij", "", []string{"Fijian"}},
+	{"fo", "fao", "", []string{"Faroese"}},
+	{"fr", "fra", "fre", []string{"French", "Français"}},
+	{"fy", "fry", "", []string{"Western Frisian", "Frisian"}},
+	{"ga", "gle", "", []string{"Irish"}},
+	{"gd", "gla", "", []string{"Scottish Gaelic", "Gaelic"}},
+	{"gl", "glg", "", []string{"Galician"}},
+	{"gn", "grn", "", []string{"Guarani"}},
+	{"gu", "guj", "", []string{"Gujarati"}},
+	{"gv", "glv", "", []string{"Manx"}},
+	{"ha", "hau", "", []string{"Hausa"}},
+	{"he", "heb", "", []string{"Hebrew", "עברית"}},
+	{"hi", "hin", "", []string{"Hindi", "हिन्दी"}},
+	{"ho", "hmo", "", []string{"Hiri Motu"}},
+	{"hr", "hrv", "", []string{"Croatian", "Hrvatski"}},
+	{"ht", "hat", "", []string{"Haitian", "Haitian Creole"}},
+	{"hu", "hun", "", []string{"Hungarian", "Magyar"}},
+	{"hy", "hye", "arm", []string{"Armenian"}},
+	{"hz", "her", "", []string{"Herero"}},
+	{"ia", "ina", "", []string{"Interlingua"}},
+	{"id", "ind", "", []string{"Indonesian", "Bahasa Indonesia"}},
+	{"ie", "ile", "", []string{"Interlingue"}},
+	{"ig", "ibo", "", []string{"Igbo"}},
+	{"ii", "iii", "", []string{"Sichuan Yi"}},
+	{"ik", "ipk", "", []string{"Inupiaq"}},
+	{"io", "ido", "", []string{"Ido"}},
+	{"is", "isl", "ice", []string{"Icelandic", "Íslenska"}},
+	{"it", "ita", "", []string{"Italian", "Italiano"}},
+	{"iu", "iku", "", []string{"Inuktitut"}},
+	{"ja", "jpn", "", []string{"Japanese", "日本語"}},
+	{"jv", "jav", "", []string{"Javanese"}},
+	{"ka", "kat", "geo", []string{"Georgian"}},
+	{"kg", "kon", "", []string{"Kongo"}},
+	{"ki", "kik", "", []string{"Kikuyu"}},
+	{"kj", "kua", "", []string{"Kuanyama"}},
+	{"kk", "kaz", "", []string{"Kazakh"}},
+	{"kl", "kal", "", []string{"Kalaallisut", "Greenlandic"}},
+	{"km", "khm", "", []string{"Khmer", "Cambodian"}},
+	{"kn", "kan", "", []string{"Kannada"}},
+	{"ko", "kor", "", []string{"Korean", "한국어"}},
+	{"kr", "kau", "", []string{"Kanuri"}},
+	{"ks", "kas", "", []string{"Kashmiri"}},
+	{"ku", "kur", "", []string{"Kurdish"}},
+	{"kv", "kom", "", []string{"Komi"}},
+	{"kw", "cor", "", []string{"Cornish"}},
+	{"ky", "kir", "", []string{"Kyrgyz", "Kirghiz"}},
+	{"la", "lat", "", []string{"Latin"}},
+	{"lb", "ltz", "", []string{"Luxembourgish"}},
+	{"lg", "lug", "", []string{"Ganda"}},
+	{"li", "lim", "", []string{"Limburgish"}},
+	{"ln", "lin", "", []string{"Lingala"}},
+	{"lo", "lao", "", []string{"Lao"}},
+	{"lt", "lit", "", []string{"Lithuanian"}},
+	{"lu", "lub", "", []string{"Luba-Katanga"}},
+	{"lv", "lav", "", []string{"Latvian"}},
+	{"mg", "mlg", "", []string{"Malagasy"}},
+	{"mh", "mah", "", []string{"Marshallese"}},
+	{"mi", "mri", "mao", []string{"Maori"}},
+	{"mk", "mkd", "mac", []string{"Macedonian"}},
+	{"ml", "mal", "", []string{"Malayalam"}},
+	{"mn", "mon", "", []string{"Mongolian"}},
+	{"mr", "mar", "", []string{"Marathi"}},
+	{"ms", "msa", "may", []string{"Malay", "Bahasa Melayu"}},
+	{"mt", "mlt", "", []string{"Maltese"}},
+	{"my", "mya", "bur", []string{"Burmese"}},
+	{"na", "nau", "", []string{"Nauru"}},
+	{"nb", "nob", "", []string{"Norwegian Bokmål", "Bokmål"}},
+	{"nd", "nde", "", []string{"North Ndebele"}},
+	{"ne", "nep", "", []string{"Nepali"}},
+	{"ng", "ndo", "", []string{"Ndonga"}},
+	{"nl", "nld", "dut", []string{"Dutch", "Flemish", "Nederlands"}},
+	{"nn", "nno", "", []string{"Norwegian Nynorsk", "Nynorsk"}},
+	{"no", "nor", "", []string{"Norwegian", "Norsk"}},
+	{"nr", "nbl", "", []string{"South Ndebele"}},
+	{"nv", "nav", "", []string{"Navajo"}},
+	{"ny", "nya", "", []string{"Chichewa", "Nyanja"}},
+	{"oc", "oci", "", []string{"Occitan"}},
+	{"oj", "oji", "", []string{"Ojibwa"}},
+	{"om", "orm", "", []string{"Oromo"}},
+	{"or", "ori", "", []string{"Oriya", "Odia"}},
+	{"os", "oss", "", []string{"Ossetian"}},
+	{"pa", "pan", "", []string{"Punjabi", "Panjabi"}},
+	{"pi", "pli", "", []string{"Pali"}},
+	{"pl", "pol", "", []string{"Polish", "Polski"}},
+	{"ps", "pus", "", []string{"Pashto"}},
+	{"pt", "por", "", []string{"Portuguese", "Português"}},
+	{"qu", "que", "", []string{"Quechua"}},
+	{"rm", "roh", "", []string{"Romansh"}},
+	{"rn", "run", "", []string{"Kirundi", "Rundi"}},
+	{"ro", "ron", "rum", []string{"Romanian", "Română"}},
+	{"ru", "rus", "", []string{"Russian", "Русский"}},
+	{"rw", "kin", "", []string{"Kinyarwanda"}},
+	{"sa", "san", "", []string{"Sanskrit"}},
+	{"sc", "srd", "", []string{"Sardinian"}},
+	{"sd", "snd", "", []string{"Sindhi"}},
+	{"se", "sme", "", []string{"Northern Sami"}},
+	{"sg", "sag", "", []string{"Sango"}},
+	{"si", "sin", "", []string{"Sinhala", "Sinhalese"}},
+	{"sk", "slk", "slo", []string{"Slovak", "Slovenčina"}},
+	{"sl", "slv", "", []string{"Slovenian", "Slovene"}},
+	{"sm", "smo", "", []string{"Samoan"}},
+	{"sn", "sna", "", []string{"Shona"}},
+	{"so", "som", "", []string{"Somali"}},
+	{"sq", "sqi", "alb", []string{"Albanian"}},
+	{"sr", "srp", "", []string{"Serbian", "Српски"}},
+	{"ss", "ssw", "", []string{"Swati"}},
+	{"st", "sot", "", []string{"Southern Sotho", "Sesotho"}},
+	{"su", "sun", "", []string{"Sundanese"}},
+	{"sv", "swe", "", []string{"Swedish", "Svenska"}},
+	{"sw", "swa", "", []string{"Swahili", "Kiswahili"}},
+	{"ta", "tam", "", []string{"Tamil"}},
+	{"te", "tel", "", []string{"Telugu"}},
+	{"tg", "tgk", "", []string{"Tajik"}},
+	{"th", "tha", "", []string{"Thai", "ไทย"}},
+	{"ti", "tir", "", []string{"Tigrinya"}},
+	{"tk", "tuk", "", []string{"Turkmen"}},
+	{"tl", "tgl", "", []string{"Tagalog", "Filipino"}},
+	{"tn", "tsn", "", []string{"Tswana"}},
+	{"to", "ton", "", []string{"Tongan"}},
+	{"tr", "tur", "", []string{"Turkish", "Türkçe"}},
+	{"ts", "tso", "", []string{"Tsonga"}},
+	{"tt", "tat", "", []string{"Tatar"}},
+	{"tw", "twi", "", []string{"Twi"}},
+	{"ty", "tah", "", []string{"Tahitian"}},
+	{"ug", "uig", "", []string{"Uyghur", "Uighur"}},
+	{"uk", "ukr", "", []string{"Ukrainian", "Українська"}},
+	{"ur", "urd", "", []string{"Urdu"}},
+	{"uz", "uzb", "", []string{"Uzbek"}},
+	{"ve", "ven", "", []string{"Venda"}},
+	{"vi", "vie", "", []string{"Vietnamese", "Tiếng Việt"}},
+	{"vo", "vol", "", []string{"Volapük"}},
+	{"wa", "wln", "", []string{"Walloon"}},
+	{"wo", "wol", "", []string{"Wolof"}},
+	{"xh", "xho", "", []string{"Xhosa"}},
+	{"yi", "yid", "", []string{"Yiddish"}},
+	{"yo", "yor", "", []string{"Yoruba"}},
+	{"za", "zha", "", []string{"Zhuang"}},
+	{"zh", "zho", "chi", []string{"Chinese", "中文"}},
+	{"zu", "zul", "", []string{"Zulu"}},
+}
+
+// deprecatedCodes are the codes that were replaced, google still uses some of the ISO 639-1 ones and open library uses
+// the MARC ones
+var deprecatedCodes = map[string]string{
+	"iw":  "he",
+	"in":  "id",
+	"ji":  "yi",
+	"jw":  "jv",
+	"mo":  "ro",
+	"cam": "km",
+	"esp": "eo",
+	"far": "fo",
+	"fri": "fy",
+	"gae": "gd",
+	"gag": "gl",
+	"int": "ia",
+	"iri": "ga",
+	"lan": "oc",
+	"max": "gv",
+	"mla": "mg",
+	"mol": "ro",
+	"sao": "sm",
+	"scc": "sr",
+	"scr": "hr",
+	"sso": "st",
+	"swz": "ss",
+	"tag": "tl",
+	"tar": "tt",
+	"tsw": "tn",
+}
+
+// otherCodes are the ISO 639-2 codes of the languages and language groups that don't have an ISO 639-1 code, including
+// the code for multiple languages
+var otherCodes = []string{
+	"ace", "ach", "ada", "ady", "afa", "afh", "ain", "akk", "ale", "alg", "alt", "ang", "anp", "apa", "arc", "arn",
+	"arp", "art", "arw", "ast", "ath", "aus", "awa",
+	"bad", "bai", "bal", "ban", "bas", "bat", "bej", "bem", "ber", "bho", "bik", "bin", "bla", "bnt", "bra", "btk",
+	"bua", "bug", "byn",
+	"cad", "cai", "car", "cau", "ceb", "cel", "chb", "chg", "chk", "chm", "chn", "cho", "chp", "chr", "chy", "cmc",
+	"cnr", "cop", "cpe", "cpf", "cpp", "crh", "crp", "csb", "cus",
+	"dak", "dar", "day", "del", "den", "dgr", "din", "doi", "dra", "dsb", "dua", "dum", "dyu",
+	"efi", "egy", "eka", "elx", "enm", "ewo",
+	"fan", "fat", "fil", "fiu", "fon", "frm", "fro", "frr", "frs", "fur",
+	"gaa", "gay", "gba", "gem", "gez", "gil", "gmh", "goh", "gon", "gor", "got", "grb", "grc", "gsw", "gwi",
+	"hai", "haw", "hil", "him", "hit", "hmn", "hsb", "hup",
+	"iba", "ijo", "ilo", "inc", "ine", "inh", "ira", "iro",
+	"jbo", "jpr", "jrb",
+	"kaa", "kab", "kac", "kam", "kar", "kaw", "kbd", "kha", "khi", "kho", "kmb", "kok", "kos", "kpe", "krc", "krl",
+	"kro", "kru", "kum", "kut",
+	"lad", "lah", "lam", "lez", "lol", "loz", "lua", "lui", "lun", "luo", "lus",
+	"mad", "mag", "mai", "mak", "man", "map", "mas", "mdf", "mdr", "men", "mga", "mic", "min", "mkh", "mnc",
+	"mni", "mno", "moh", "mos", "mul", "mun", "mus", "mwl", "mwr", "myn", "myv",
+	"nah", "nai", "nap", "nds", "new", "nia", "nic", "niu", "nog", "non", "nqo", "nso", "nub", "nwc", "nym", "nyn",
+	"nyo", "nzi",
+	"osa", "ota", "oto",
+	"paa", "pag", "pal", "pam", "pap", "pau", "peo", "phi", "phn", "pon", "pra", "pro",
+	"raj", "rap", "rar", "roa", "rom", "rup",
+	"sad", "sah", "sai", "sal", "sam", "sas", "sat", "scn", "sco", "sel", "sem", "sga", "sgn", "shn", "sid", "sio",
+	"sit", "sla", "sma", "smi", "smj", "smn", "sms", "snk", "sog", "son", "srn", "srr", "ssa", "suk", "sus", "sux",
+	"syc", "syr",
+	"tai", "tem", "ter", "tet", "tig", "tiv", "tkl", "tlh", "tli", "tmh", "tog", "tpi", "tsi", "tum", "tup", "tut",
+	"tvl", "tyv",
+	"udm", "uga", "umb",
+	"vai", "vot",
+	"wak", "wal", "war", "was", "wen",
+	"xal",
+	"yao", "yap", "ypk",
+	"zap", "zbl", "zen", "zgh", "znd", "zun", "zza",
+}
+
+// unknownCodes are the ISO 639-2 codes for undetermined, uncoded and no linguistic content
+var unknownCodes = []string{"und", "mis", "zxx"}
+
+// codes are the ISO 639-1 codes by every code and lower case name of their language
+var codes = getCodes()
+
+func getCodes() map[string]string {
+	codes := make(map[string]string)
+	for _, language := range languages {
+		codes[language.code] = language.code
+		codes[language.terminologyCode] = language.code
+		if language.bibliographicCode != "" {
+			codes[language.bibliographicCode] = language.code
+		}
+
+		for _, name := range language.names {
+			codes[strings.ToLower(name)] = language.code
+		}
+	}
+
+	for deprecatedCode, code := range deprecatedCodes {
+		codes[deprecatedCode] = code
+	}
+
+	for _, code := range otherCodes {
+		codes[code] = code
+	}
+
+	return codes
+}
+
+// Normalize returns the ISO 639-1 code of a language code or name, like "en" for "eng", "English" or "en_US". The ISO
+// 639-2 and 639-3 codes of languages that don't have an ISO 639-1 code are returned as they are, or as the code of their
+// macrolanguage like "zh" for "cmn". It returns false if the value is not a known language.
+func Normalize(value string) (string, bool) {
+	value = strings.ToLower(strings.TrimSpace(value))
+
+	// only the first language of a list is kept
+	value, _, _ = strings.Cut(value, ";")
+	value, _, _ = strings.Cut(value, ",")
+	value, _, _ = strings.Cut(value, "(")
+	value = strings.TrimSpace(value)
+
+	// the region or script of a locale is removed
+	subtag, _, hasRegion := strings.Cut(strings.ReplaceAll(value, "_", "-"), "-")
+	if hasRegion && isLetters(subtag) && (len(subtag) == 2 || len(subtag) == 3) {
+		value = subtag
+	}
+
+	code, isKnown := codes[value]
+	if isKnown {
+		return code, true
+	}
+
+	return normalizeIso6393(value)
+}
+
+// normalizeIso6393 maps the ISO 639-3 codes that are not ISO 639-2 codes, private use codes are not known languages
+func normalizeIso6393(value string) (string, bool) {
+	if len(value) != 3 || !isLetters(value) || slices.Contains(unknownCodes, value) || (value >= "qaa" && value <= "qtz") {
+		return "", false
+	}
+
+	tag, err := textlanguage.All.Parse(value)
+	if err != nil {
+		return "", false
+	}
+
+	base, _ := tag.Base()
+	code, isKnown := codes[base.String()]
+	if isKnown {
+		return code, true
+	}
+
+	return base.String(), true
+}
+
+func isLetters(value string) bool {
+	for _, char := range value {
+		if char > unicode.MaxASCII || !unicode.IsLetter(char) {
+			return false
+		}
+	}
+
+	return value != ""
+}
@@ -0,0 +1,58 @@
+package language
+
+import (
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    string
+		isKnown bool
+	}{
+		{value: "en", want: "en", isKnown: true},
+		{value: "EN", want: "en", isKnown: true},
+		{value: "eng", want: "en", isKnown: true},
+		{value: "English", want: "en", isKnown: true},
+		{value: " english ", want: "en", isKnown: true},
+		{value: "en_US", want: "en", isKnown: true},
+		{value: "en-GB", want: "en", isKnown: true},
+		{value: "zh-Hant", want: "zh", isKnown: true},
+		{value: "ger", want: "de", isKnown: true},
+		{value: "deu", want: "de", isKnown: true},
+		{value: "Deutsch", want: "de", isKnown: true},
+		{value: "fre", want: "fr", isKnown: true},
+		{value: "Français", want: "fr", isKnown: true},
+		{value: "iw", want: "he", isKnown: true},
+		{value: "scc", want: "sr", isKnown: true},
+		{value: "English; French", want: "en", isKnown: true},
+		{value: "Spanish, English", want: "es", isKnown: true},
+		{value: "Greek (Modern)", want: "el", isKnown: true},
+		{value: "ast", want: "ast", isKnown: true},
+		{value: "grc", want: "grc", isKnown: true},
+		{value: "mul", want: "mul", isKnown: true},
+		{value: "cmn", want: "zh", isKnown: true},
+		{value: "arb", want: "ar", isKnown: true},
+		{value: "yue", want: "yue", isKnown: true},
+		{value: "nan", want: "nan", isKnown: true},
+		{value: "und", isKnown: false},
+		{value: "zxx", isKnown: false},
+		{value: "mis", isKnown: false},
+		{value: "qaa", isKnown: false},
+		{value: "xyz", isKnown: false},
+		{value: "zzz", isKnown: false},
+		{value: "n/a", isKnown: false},
+		{value: "Elvish", isKnown: false},
+		{value: "e1", isKnown: false},
+		{value: "", isKnown: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.value, func(t *testing.T) {
+			got, isKnown := Normalize(test.value)
+			if got != test.want || isKnown != test.isKnown {
+				t.Errorf("Normalize(%q) = %q, %v, want %q, %v", test.value, got, isKnown, test.want, test.isKnown)
+			}
+		})
+	}
+}
@@ -0,0 +1,183 @@
+package provider
+
+import (
+	"context"
+	"log"
+	"math"
+	"sync"
+
+	"github.com/zaelmyth/book-data-collector/internal/configuration"
+)
+
+// Provider is a book data source that the collector can search. Implementations map their api responses to records so
+// the collector and the database code do not have to know which api the data came from.
+type Provider interface {
+	// IdColumn is the books table column that uniquely identifies a record from this provider
+	IdColumn() string
+	// IsbnBatchSize is the max number of isbns that can be looked up with a single call
+	IsbnBatchSize() int
+	// MaxCallsPerSecond is used when the calls per second are not configured
+	MaxCallsPerSecond() int
+	// MaxCallsPerDay is used when the calls per day are not configured, 0 means that there is no daily limit
+	MaxCallsPerDay() int
+	// Search returns an empty page if there are no results and one of the client errors if the call failed
+	Search(ctx context.Context, query string, page int) (Page, error)
+	SearchByIsbn(ctx context.Context, isbns []string) (Page, error)
+	// SearchById looks up saved books by the values of their IdColumn to refresh them, ids are batched like isbns
+	SearchById(ctx context.Context, ids []string) (Page, error)
+	// NextPage returns the page that should be searched after the given one and false if there are no more pages
+	NextPage(page Page) (int, bool)
+	// Decode maps an archived response body of one of the endpoints the provider calls to records, the responses of
+	// endpoints that don't return books have no records
+	Decode(ctx context.Context, endpoint string, body []byte) ([]Record, error)
+}
+
+type Page struct {
+	Number  int
+	Total   int
+	Records []Record
+}
+
+// Record is a book mapped from a provider response to the columns of the books table
+type Record struct {
+	Id                  string
+	GoogleId            string
+	OpenLibraryId       string
+	Title               string
+	TitleLong           string
+	Subtitle            string
+	Isbn                string
+	Isbn13              string
+	DeweyDecimal        string
+	Binding             string
+	Publisher           string
+	Language            string
+	DatePublished       string
+	Edition             string
+	Pages               int
+	Dimensions          string
+	Overview            string
+	Image               string
+	Msrp                string
+	Excerpt             string
+	Synopsis            string
+	RelatedType         string
+	AverageRating       float64
+	RatingCount         int
+	MainCategory        string
+	Authors             []string
+	Subjects            []string
+	IndustryIdentifiers []IndustryIdentifier
+	Prices              []Price
+	// OtherIsbns are the isbns of the other formats of the same title
+	OtherIsbns           []OtherIsbn
+	Reviews              []string
+	DimensionsStructured Dimensions
+	ImageLinks           []ImageLink
+	Series               []Series
+	Access               Access
+}
+
+type IndustryIdentifier struct {
+	Type       string
+	Identifier string
+}
+
+type OtherIsbn struct {
+	Isbn    string
+	Binding string
+}
+
+// Dimensions are the physical size and weight of a book, measurements that the api didn't return have a value of 0
+type Dimensions struct {
+	Length Measurement
+	Width  Measurement
+	Height Measurement
+	Weight Measurement
+}
+
+type Measurement struct {
+	Unit  string
+	Value float64
+}
+
+type ImageLink struct {
+	Size string
+	Url  string
+}
+
+// Series is a series that a book is part of and its position in the series
+type Series struct {
+	SeriesId    string
+	BookType    string
+	OrderNumber int
+}
+
+// Access is how a book can be read and who it is meant for, it's empty if the api didn't return it
+type Access struct {
+	Country                string
+	Viewability            string
+	AccessViewStatus       string
+	TextToSpeechPermission string
+	MaturityRating         string
+	Embeddable             bool
+	PublicDomain           bool
+	EpubAvailable          bool
+	PdfAvailable           bool
+}
+
+// Price is what a merchant asked for a book when it was collected, the amounts are nil if the api didn't return them
+type Price struct {
+	Merchant  string
+	Country   string
+	Condition string
+	Currency  string
+	ListPrice *float64
+	Price     *float64
+	Shipping  *float64
+	Total     *float64
+	Link      string
+}
+
+type Factory func(config configuration.Config) Provider
+
+var (
+	factories      = make(map[string]Factory)
+	factoriesMutex sync.RWMutex
+)
+
+// Register makes a provider available by name. It is meant to be called from the init function of the package that
+// implements the provider, the same way database/sql drivers register themselves.
+func Register(name string, factory Factory) {
+	factoriesMutex.Lock()
+	defer factoriesMutex.Unlock()
+
+	if _, isRegistered := factories[name]; isRegistered {
+		log.Fatal("Provider " + name + " is already registered")
+	}
+
+	factories[name] = factory
+}
+
+func Get(config configuration.Config) Provider {
+	factoriesMutex.RLock()
+	defer factoriesMutex.RUnlock()
+
+	factory, isRegistered := factories[config.Provider]
+	if !isRegistered {
+		log.Fatal("Provider " + config.Provider + " is not registered")
+	}
+
+	return factory(config)
+}
+
+// NextPageByTotal is the pagination most apis use, where the total number of results and the page size are enough to
+// know if there are more pages
+func NextPageByTotal(page Page, pageSize int) (int, bool) {
+	maxPage := int(math.Ceil(float64(page.Total) / float64(pageSize)))
+	if page.Number >= maxPage {
+		return 0, false
+	}
+
+	return page.Number + 1, true
+}
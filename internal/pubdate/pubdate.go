@@ -0,0 +1,101 @@
+package pubdate
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Precision is the most specific part of a date that is known
+const (
+	PrecisionYear  = "year"
+	PrecisionMonth = "month"
+	PrecisionDay   = "day"
+)
+
+// Date is a parsed publication date, the month and day are 0 if the precision doesn't include them
+type Date struct {
+	Year      int
+	Month     int
+	Day       int
+	Precision string
+}
+
+// layouts are the formats that the apis write dates in by precision, the most specific ones are tried first
+var layouts = []struct {
+	layout    string
+	precision string
+}{
+	{"2006-01-02", PrecisionDay},
+	{"2006/01/02", PrecisionDay},
+	{"January 2, 2006", PrecisionDay},
+	{"Jan 2, 2006", PrecisionDay},
+	{"January 2 2006", PrecisionDay},
+	{"Jan 2 2006", PrecisionDay},
+	{"2 January 2006", PrecisionDay},
+	{"2 Jan 2006", PrecisionDay},
+	{"2006-01", PrecisionMonth},
+	{"2006/01", PrecisionMonth},
+	{"January 2006", PrecisionMonth},
+	{"Jan 2006", PrecisionMonth},
+	{"January, 2006", PrecisionMonth},
+	{"Jan, 2006", PrecisionMonth},
+	{"2006", PrecisionYear},
+}
+
+var yearPattern = regexp.MustCompile(`\b(1[0-9]{3}|20[0-9]{2})\b`)
+
+// Parse parses the publication dates of the apis, like "2004", "2004-05", "2004-05-12", "May 2004" or "c1998". Dates
+// that can't be parsed fall back to the first year that is written in them and the precision is empty if there is none.
+func Parse(value string) Date {
+	value = clean(value)
+	if value == "" {
+		return Date{}
+	}
+
+	for _, layout := range layouts {
+		date, err := time.Parse(layout.layout, value)
+		if err != nil || date.Year() == 0 {
+			continue
+		}
+
+		parsed := Date{Year: date.Year(), Precision: layout.precision}
+		if layout.precision != PrecisionYear {
+			parsed.Month = int(date.Month())
+		}
+		if layout.precision == PrecisionDay {
+			parsed.Day = date.Day()
+		}
+
+		return parsed
+	}
+
+	year := yearPattern.FindString(value)
+	if year == "" {
+		return Date{}
+	}
+
+	date, err := time.Parse("2006", year)
+	if err != nil {
+		return Date{}
+	}
+
+	return Date{Year: date.Year(), Precision: PrecisionYear}
+}
+
+// clean removes the copyright marks, brackets, question marks and times that are added to the dates
+func clean(value string) string {
+	value = strings.TrimSpace(value)
+
+	// isbndb adds a time to some of the dates
+	date, _, hasTime := strings.Cut(value, "T")
+	if hasTime && len(date) == len("2006-01-02") {
+		value = date
+	}
+
+	value = strings.TrimLeft(value, "c©[ ")
+	value = strings.TrimRight(value, "]?. ")
+	value = strings.ReplaceAll(value, ".", "")
+
+	return strings.Join(strings.Fields(value), " ")
+}
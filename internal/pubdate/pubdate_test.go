@@ -0,0 +1,43 @@
+package pubdate
+
+import (
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		value string
+		want  Date
+	}{
+		{value: "2004", want: Date{Year: 2004, Precision: PrecisionYear}},
+		{value: "c1998", want: Date{Year: 1998, Precision: PrecisionYear}},
+		{value: "©1998", want: Date{Year: 1998, Precision: PrecisionYear}},
+		{value: "[1998?]", want: Date{Year: 1998, Precision: PrecisionYear}},
+		{value: "2004-05", want: Date{Year: 2004, Month: 5, Precision: PrecisionMonth}},
+		{value: "2004/05", want: Date{Year: 2004, Month: 5, Precision: PrecisionMonth}},
+		{value: "May 2004", want: Date{Year: 2004, Month: 5, Precision: PrecisionMonth}},
+		{value: "Sep, 2004", want: Date{Year: 2004, Month: 9, Precision: PrecisionMonth}},
+		{value: "2004-05-12", want: Date{Year: 2004, Month: 5, Day: 12, Precision: PrecisionDay}},
+		{value: "2004/05/12", want: Date{Year: 2004, Month: 5, Day: 12, Precision: PrecisionDay}},
+		{value: "2004-05-12T00:00:01+00:00", want: Date{Year: 2004, Month: 5, Day: 12, Precision: PrecisionDay}},
+		{value: "October 4, 1999", want: Date{Year: 1999, Month: 10, Day: 4, Precision: PrecisionDay}},
+		{value: "Oct. 4, 1999", want: Date{Year: 1999, Month: 10, Day: 4, Precision: PrecisionDay}},
+		{value: "4 October 1999", want: Date{Year: 1999, Month: 10, Day: 4, Precision: PrecisionDay}},
+		{value: "  October   4,  1999 ", want: Date{Year: 1999, Month: 10, Day: 4, Precision: PrecisionDay}},
+		{value: "2004-13-45", want: Date{Year: 2004, Precision: PrecisionYear}},
+		{value: "Spring 1998", want: Date{Year: 1998, Precision: PrecisionYear}},
+		{value: "", want: Date{}},
+		{value: "unknown", want: Date{}},
+		{value: "0000", want: Date{}},
+		{value: "12345", want: Date{}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.value, func(t *testing.T) {
+			got := Parse(test.value)
+			if got != test.want {
+				t.Errorf("Parse(%q) = %+v, want %+v", test.value, got, test.want)
+			}
+		})
+	}
+}
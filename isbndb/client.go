@@ -1,9 +1,10 @@
 package isbndb
 
 import (
+	"context"
+	"errors"
 	"log"
 	"net/url"
-	"os"
 	"slices"
 	"strconv"
 
@@ -20,29 +21,42 @@ const apiUrlPro = "https://api.pro.isbndb.com"
 const maxCallsPerSecondBasic = 1
 const maxCallsPerSecondPremium = 3
 const maxCallsPerSecondPro = 5
+const maxCallsPerDayBasic = 2000
+const maxCallsPerDayPremium = 5000
+const maxCallsPerDayPro = 15000
+
+// configuredSubscriptionType and configuredApiKey are what the api is called with, NewProvider sets them from the
+// configuration
+var configuredSubscriptionType string
+var configuredApiKey string
+
+func Configure(subscriptionType string, apiKey string) {
+	configuredSubscriptionType = subscriptionType
+	configuredApiKey = apiKey
+}
 
-func AuthorDetails(name string, page int, pageSize int, language string) (Author, int) {
+func AuthorDetails(ctx context.Context, name string, page int, pageSize int, language string) (Author, int, error) {
 	validatePagination(page, pageSize)
 
-	return call("get", "/author/"+name, url.Values{
+	return call(ctx, "get", "/author/"+name, url.Values{
 		"page":     {strconv.Itoa(page)},
 		"pageSize": {strconv.Itoa(pageSize)},
 		"language": {language},
 	}, Author{})
 }
 
-func SearchAuthors(query string, page int, pageSize int) (AuthorQueryResults, int) {
+func SearchAuthors(ctx context.Context, query string, page int, pageSize int) (AuthorQueryResults, int, error) {
 	validatePagination(page, pageSize)
 
-	return call("get", "/authors/"+query, url.Values{
+	return call(ctx, "get", "/authors/"+query, url.Values{
 		"page":     {strconv.Itoa(page)},
 		"pageSize": {strconv.Itoa(pageSize)},
 	}, AuthorQueryResults{})
 }
 
-func BookDetails(isbn string, withPrices bool) (Book, int) {
-	if withPrices && os.Getenv("ISBNDB_SUBSCRIPTION_TYPE") != "pro" {
-		log.Fatal("Book details with prices option is only available with the pro subscription")
+func BookDetails(ctx context.Context, isbn string, withPrices bool) (Book, int, error) {
+	if withPrices && configuredSubscriptionType != "pro" {
+		return Book{}, 0, errors.New("book details with prices are only available with the pro subscription")
 	}
 
 	withPricesQuery := "0"
@@ -50,26 +64,26 @@ func BookDetails(isbn string, withPrices bool) (Book, int) {
 		withPricesQuery = "1"
 	}
 
-	response, statusCode := call("get", "/book/"+isbn, url.Values{
+	response, statusCode, err := call(ctx, "get", "/book/"+isbn, url.Values{
 		"with_prices": {withPricesQuery},
 	}, struct {
 		Book Book
 	}{})
 
-	return response.Book, statusCode
+	return response.Book, statusCode, err
 }
 
-func SearchBooksByIsbn(isbns []string) (BookSearchByIsbnResults, int) {
+func SearchBooksByIsbn(ctx context.Context, isbns []string) (BookSearchByIsbnResults, int, error) {
 	if len(isbns) > 1000 {
 		log.Fatal("Number of ISBNs cannot be bigger than 1000")
 	}
 
-	return call("post", "/books", url.Values{
+	return call(ctx, "post", "/books", url.Values{
 		"isbns": isbns,
 	}, BookSearchByIsbnResults{})
 }
 
-func SearchBooksByQuery(request BookSearchByQueryRequest) (BookSearchByQueryResults, int) {
+func SearchBooksByQuery(ctx context.Context, request BookSearchByQueryRequest) (BookSearchByQueryResults, int, error) {
 	validatePagination(request.Page, request.PageSize)
 	if !slices.Contains([]string{"", "title", "author", "date_published", "subjects"}, request.Column) {
 		log.Fatal("Invalid column")
@@ -96,29 +110,29 @@ func SearchBooksByQuery(request BookSearchByQueryRequest) (BookSearchByQueryResu
 		data.Add("edition", strconv.Itoa(request.Edition))
 	}
 
-	return call("get", "/books/"+request.Query, data, BookSearchByQueryResults{})
+	return call(ctx, "get", "/books/"+request.Query, data, BookSearchByQueryResults{})
 }
 
-func PublisherDetails(name string, page int, pageSize int, language string) (Publisher, int) {
+func PublisherDetails(ctx context.Context, name string, page int, pageSize int, language string) (Publisher, int, error) {
 	validatePagination(page, pageSize)
 
-	return call("get", "/publisher/"+name, url.Values{
+	return call(ctx, "get", "/publisher/"+name, url.Values{
 		"page":     {strconv.Itoa(page)},
 		"pageSize": {strconv.Itoa(pageSize)},
 		"language": {language},
 	}, Publisher{})
 }
 
-func SearchPublishers(query string, page int, pageSize int) (PublisherQueryResults, int) {
+func SearchPublishers(ctx context.Context, query string, page int, pageSize int) (PublisherQueryResults, int, error) {
 	validatePagination(page, pageSize)
 
-	return call("get", "/publishers/"+query, url.Values{
+	return call(ctx, "get", "/publishers/"+query, url.Values{
 		"page":     {strconv.Itoa(page)},
 		"pageSize": {strconv.Itoa(pageSize)},
 	}, PublisherQueryResults{})
 }
 
-func SearchByIndex(request SearchRequest) (SearchResultsNames, int) {
+func SearchByIndex(ctx context.Context, request SearchRequest) (SearchResultsNames, int, error) {
 	validatePagination(request.Page, request.PageSize)
 	if !slices.Contains([]string{"authors", "subjects", "publishers"}, request.Index) {
 		log.Fatal("Invalid index")
@@ -150,10 +164,10 @@ func SearchByIndex(request SearchRequest) (SearchResultsNames, int) {
 		data.Add("publisher", request.Publisher)
 	}
 
-	return call("get", "/search/"+request.Index, data, SearchResultsNames{})
+	return call(ctx, "get", "/search/"+request.Index, data, SearchResultsNames{})
 }
 
-func SearchByIndexBooks(request SearchRequest) (SearchResultsBooks, int) {
+func SearchByIndexBooks(ctx context.Context, request SearchRequest) (SearchResultsBooks, int, error) {
 	validatePagination(request.Page, request.PageSize)
 	if request.Index != "books" {
 		log.Fatal("Invalid index")
@@ -185,21 +199,21 @@ func SearchByIndexBooks(request SearchRequest) (SearchResultsBooks, int) {
 		data.Add("publisher", request.Publisher)
 	}
 
-	return call("get", "/search/"+request.Index, data, SearchResultsBooks{})
+	return call(ctx, "get", "/search/"+request.Index, data, SearchResultsBooks{})
 }
 
-func GetStats() (Stats, int) {
-	return call("get", "/stats", url.Values{}, Stats{})
+func GetStats(ctx context.Context) (Stats, int, error) {
+	return call(ctx, "get", "/stats", url.Values{}, Stats{})
 }
 
-func SubjectDetails(name string) (Subject, int) {
-	return call("get", "/subject/"+name, url.Values{}, Subject{})
+func SubjectDetails(ctx context.Context, name string) (Subject, int, error) {
+	return call(ctx, "get", "/subject/"+name, url.Values{}, Subject{})
 }
 
-func SearchSubjects(query string, page int, pageSize int) (SubjectQueryResults, int) {
+func SearchSubjects(ctx context.Context, query string, page int, pageSize int) (SubjectQueryResults, int, error) {
 	validatePagination(page, pageSize)
 
-	return call("get", "/subjects/"+query, url.Values{
+	return call(ctx, "get", "/subjects/"+query, url.Values{
 		"page":     {strconv.Itoa(page)},
 		"pageSize": {strconv.Itoa(pageSize)},
 	}, SubjectQueryResults{})
@@ -209,15 +223,13 @@ type SubscriptionParams struct {
 	Type              string
 	ApiUrl            string
 	MaxCallsPerSecond int
-	// todo: add and implement MaxCallsPerDay
+	MaxCallsPerDay    int
 }
 
-func GetSubscriptionParams() SubscriptionParams {
+func GetSubscriptionParams(subscriptionType string) (SubscriptionParams, error) {
 	validSubscriptionTypes := []string{"basic", "premium", "pro"}
-	subscriptionType := os.Getenv("ISBNDB_SUBSCRIPTION_TYPE")
-
 	if !slices.Contains(validSubscriptionTypes, subscriptionType) {
-		log.Fatal("Not set or invalid ISBNDB_SUBSCRIPTION_TYPE")
+		return SubscriptionParams{}, errors.New("not set or invalid isbndb subscription type " + subscriptionType)
 	}
 
 	if subscriptionType == "basic" {
@@ -225,7 +237,8 @@ func GetSubscriptionParams() SubscriptionParams {
 			Type:              subscriptionType,
 			ApiUrl:            apiUrlBasic,
 			MaxCallsPerSecond: maxCallsPerSecondBasic,
-		}
+			MaxCallsPerDay:    maxCallsPerDayBasic,
+		}, nil
 	}
 
 	if subscriptionType == "premium" {
@@ -233,26 +246,29 @@ func GetSubscriptionParams() SubscriptionParams {
 			Type:              subscriptionType,
 			ApiUrl:            apiUrlPremium,
 			MaxCallsPerSecond: maxCallsPerSecondPremium,
-		}
+			MaxCallsPerDay:    maxCallsPerDayPremium,
+		}, nil
 	}
 
 	return SubscriptionParams{
 		Type:              subscriptionType,
 		ApiUrl:            apiUrlPro,
 		MaxCallsPerSecond: maxCallsPerSecondPro,
-	}
+		MaxCallsPerDay:    maxCallsPerDayPro,
+	}, nil
 }
 
-func call[T any](method string, url string, data url.Values, responseStruct T) (T, int) {
-	// todo: use config
-	apiUrl := GetSubscriptionParams().ApiUrl
+func call[T any](ctx context.Context, method string, url string, data url.Values, responseStruct T) (T, int, error) {
+	subscription, err := GetSubscriptionParams(configuredSubscriptionType)
+	if err != nil {
+		return responseStruct, 0, err
+	}
 
-	isbndbApiKey := os.Getenv("ISBNDB_API_KEY")
-	if isbndbApiKey == "" {
-		log.Fatal("ISBNDB_API_KEY is not set")
+	if configuredApiKey == "" {
+		return responseStruct, 0, errors.New("isbndb api key is not set")
 	}
 
-	return client.Call(method, apiUrl+url, data, map[string]string{"Authorization": isbndbApiKey}, responseStruct)
+	return client.Call(ctx, method, subscription.ApiUrl+url, data, map[string]string{"Authorization": configuredApiKey}, responseStruct)
 }
 
 func validatePagination(page int, pageSize int) {
@@ -0,0 +1,257 @@
+package isbndb
+
+import (
+	"context"
+	"encoding/json"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/zaelmyth/book-data-collector/internal/configuration"
+	"github.com/zaelmyth/book-data-collector/internal/isbn"
+	"github.com/zaelmyth/book-data-collector/internal/provider"
+)
+
+func init() {
+	provider.Register("isbndb", NewProvider)
+}
+
+type Provider struct {
+	column string
+	// withPrices looks up isbns one at a time with the book details call, which is the only one that returns prices
+	withPrices   bool
+	subscription SubscriptionParams
+}
+
+func NewProvider(config configuration.Config) provider.Provider {
+	column := config.SearchBy
+	if column == "subject" {
+		column = "subjects"
+	}
+
+	Configure(config.IsbndbSubscriptionType, config.IsbndbApiKey)
+
+	// the subscription type is validated by the configuration, the calls fail with its error if it's not valid
+	subscription, _ := GetSubscriptionParams(config.IsbndbSubscriptionType)
+
+	return Provider{column: column, withPrices: config.WatchPrices, subscription: subscription}
+}
+
+func (p Provider) IdColumn() string {
+	return "isbn13"
+}
+
+func (p Provider) IsbnBatchSize() int {
+	if p.withPrices {
+		return 1
+	}
+
+	return 1000
+}
+
+func (p Provider) MaxCallsPerSecond() int {
+	return p.subscription.MaxCallsPerSecond
+}
+
+func (p Provider) MaxCallsPerDay() int {
+	return p.subscription.MaxCallsPerDay
+}
+
+func (p Provider) Search(ctx context.Context, query string, page int) (provider.Page, error) {
+	results, _, err := SearchBooksByQuery(ctx, BookSearchByQueryRequest{
+		Query:    query,
+		Page:     page,
+		PageSize: MaxPageSize,
+		Column:   p.column,
+	})
+
+	return provider.Page{
+		Number:  page,
+		Total:   results.Total,
+		Records: toRecords(results.Books),
+	}, err
+}
+
+func (p Provider) SearchByIsbn(ctx context.Context, isbns []string) (provider.Page, error) {
+	if p.withPrices {
+		return searchByIsbnWithPrices(ctx, isbns[0])
+	}
+
+	results, _, err := SearchBooksByIsbn(ctx, isbns)
+
+	return provider.Page{
+		Number:  1,
+		Total:   results.Total,
+		Records: toRecords(results.Data),
+	}, err
+}
+
+// SearchById searches by isbn because the id of a book is its isbn-13
+func (p Provider) SearchById(ctx context.Context, ids []string) (provider.Page, error) {
+	return p.SearchByIsbn(ctx, ids)
+}
+
+// NextPage stops at MaxReturnSize because the api refuses to return results past it no matter how many there are
+func (p Provider) NextPage(page provider.Page) (int, bool) {
+	page.Total = min(page.Total, MaxReturnSize)
+
+	return provider.NextPageByTotal(page, MaxPageSize)
+}
+
+func (p Provider) Decode(ctx context.Context, endpoint string, body []byte) ([]provider.Record, error) {
+	switch {
+	case endpoint == "/books":
+		var results BookSearchByIsbnResults
+		err := json.Unmarshal(body, &results)
+		return toRecords(results.Data), err
+	case strings.HasPrefix(endpoint, "/books/"):
+		var results BookSearchByQueryResults
+		err := json.Unmarshal(body, &results)
+		return toRecords(results.Books), err
+	case strings.HasPrefix(endpoint, "/book/"):
+		var response struct {
+			Book Book
+		}
+		err := json.Unmarshal(body, &response)
+		if err != nil {
+			return nil, err
+		}
+		return bookToRecords(response.Book), nil
+	default:
+		return nil, nil
+	}
+}
+
+// searchByIsbnWithPrices returns an empty page if the book was not found, the api responds with an empty book
+func searchByIsbnWithPrices(ctx context.Context, bookIsbn string) (provider.Page, error) {
+	book, _, err := BookDetails(ctx, bookIsbn, true)
+	records := bookToRecords(book)
+
+	return provider.Page{
+		Number:  1,
+		Total:   len(records),
+		Records: records,
+	}, err
+}
+
+func toRecords(books []Book) []provider.Record {
+	records := make([]provider.Record, 0, len(books))
+	for _, book := range books {
+		records = append(records, toRecord(book))
+	}
+
+	return records
+}
+
+// bookToRecords returns no records for the empty book of a not found response
+func bookToRecords(book Book) []provider.Record {
+	record := toRecord(book)
+	if record.Id == "" {
+		return nil
+	}
+
+	return []provider.Record{record}
+}
+
+// toRecord identifies books by their isbn-13, books without a valid isbn have no id and are not saved
+func toRecord(book Book) provider.Record {
+	isbn13, isbn10 := isbn.Normalize(book.Isbn13, book.Isbn)
+
+	return provider.Record{
+		Id:            isbn13,
+		Title:         book.Title,
+		TitleLong:     book.TitleLong,
+		Isbn:          isbn10,
+		Isbn13:        isbn13,
+		DeweyDecimal:  strings.Join(book.DeweyDecimal, ", "),
+		Binding:       book.Binding,
+		Publisher:     book.Publisher,
+		Language:      book.Language,
+		DatePublished: book.DatePublished,
+		Edition:       string(book.Edition),
+		Pages:         book.Pages,
+		Dimensions:    string(book.Dimensions),
+		Overview:      book.Overview,
+		Image:         book.Image,
+		Msrp:          string(book.Msrp),
+		Excerpt:       book.Excerpt,
+		Synopsis:      book.Synopsis,
+		RelatedType:   book.Related.Type,
+		Authors:       book.Authors,
+		Subjects:      book.Subjects,
+		Prices:        toPrices(book.Prices),
+		OtherIsbns:    toOtherIsbns(book),
+		Reviews:       book.Reviews,
+		DimensionsStructured: provider.Dimensions{
+			Length: provider.Measurement(book.DimensionsStructured.Length),
+			Width:  provider.Measurement(book.DimensionsStructured.Width),
+			Height: provider.Measurement(book.DimensionsStructured.Height),
+			Weight: provider.Measurement(book.DimensionsStructured.Weight),
+		},
+	}
+}
+
+func toOtherIsbns(book Book) []provider.OtherIsbn {
+	otherIsbns := make([]provider.OtherIsbn, 0, len(book.OtherIsbns))
+	for _, otherIsbn := range book.OtherIsbns {
+		isbn13, isValid := isbn.To13(otherIsbn.Isbn)
+		if !isValid {
+			continue
+		}
+
+		otherIsbns = append(otherIsbns, provider.OtherIsbn{
+			Isbn:    isbn13,
+			Binding: otherIsbn.Binding,
+		})
+	}
+
+	return otherIsbns
+}
+
+func toPrices(merchants []Merchant) []provider.Price {
+	prices := make([]provider.Price, 0, len(merchants))
+	for _, merchant := range merchants {
+		prices = append(prices, provider.Price{
+			Merchant:  merchant.Merchant,
+			Condition: merchant.Condition,
+			Price:     parsePrice(merchant.Price),
+			Shipping:  parsePrice(merchant.Shipping),
+			Total:     parsePrice(merchant.Total),
+			Link:      merchant.Link,
+		})
+	}
+
+	return prices
+}
+
+// decimalCommaPrice matches the prices that use a comma as the decimal separator, like "12,50" or "1.234,50"
+var decimalCommaPrice = regexp.MustCompile(`^(\d+|\d{1,3}(\.\d{3})+),\d{2}$`)
+
+// thousandsCommaPrice matches the prices that use a comma as the thousands separator, like "1,234" or "1,234.50"
+var thousandsCommaPrice = regexp.MustCompile(`^\d{1,3}(,\d{3})+(\.\d+)?$`)
+
+// parsePrice returns nil for prices that are missing or not numbers, the api returns prices as strings that can have a
+// currency symbol or say that shipping is free. Prices with a comma that is neither a decimal nor a thousands separator
+// are ambiguous and are not saved.
+func parsePrice(price string) *float64 {
+	price = strings.TrimSpace(strings.ReplaceAll(price, "$", ""))
+	if strings.EqualFold(price, "free") {
+		price = "0"
+	}
+
+	switch {
+	case decimalCommaPrice.MatchString(price):
+		price = strings.ReplaceAll(strings.ReplaceAll(price, ".", ""), ",", ".")
+	case thousandsCommaPrice.MatchString(price):
+		price = strings.ReplaceAll(price, ",", "")
+	case strings.Contains(price, ","):
+		return nil
+	}
+
+	amount, err := strconv.ParseFloat(price, 64)
+	if err != nil {
+		return nil
+	}
+
+	return &amount
+}
@@ -0,0 +1,70 @@
+package isbndb
+
+import (
+	"context"
+	"testing"
+
+	"github.com/zaelmyth/book-data-collector/internal/configuration"
+)
+
+func TestParsePrice(t *testing.T) {
+	tests := []struct {
+		price string
+		want  float64
+		isNil bool
+	}{
+		{price: "12.50", want: 12.5},
+		{price: "$12.50", want: 12.5},
+		{price: " 12.50 ", want: 12.5},
+		{price: "12", want: 12},
+		{price: "Free", want: 0},
+		{price: "12,50", want: 12.5},
+		{price: "1.234,50", want: 1234.5},
+		{price: "1,234", want: 1234},
+		{price: "1,234.50", want: 1234.5},
+		{price: "1,234,567.89", want: 1234567.89},
+		{price: "12,5", isNil: true},
+		{price: "12,500,5", isNil: true},
+		{price: "1,23.45", isNil: true},
+		{price: "", isNil: true},
+		{price: "n/a", isNil: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.price, func(t *testing.T) {
+			got := parsePrice(test.price)
+			if test.isNil {
+				if got != nil {
+					t.Errorf("parsePrice(%q) = %v, want nil", test.price, *got)
+				}
+				return
+			}
+
+			if got == nil || *got != test.want {
+				t.Errorf("parsePrice(%q) = %v, want %v", test.price, got, test.want)
+			}
+		})
+	}
+}
+
+func TestNewProviderUsesTheConfiguredSubscription(t *testing.T) {
+	bookProvider := NewProvider(configuration.Config{IsbndbSubscriptionType: "pro", IsbndbApiKey: "key", WatchPrices: true})
+	defer Configure("", "")
+
+	if bookProvider.MaxCallsPerSecond() != maxCallsPerSecondPro || bookProvider.MaxCallsPerDay() != maxCallsPerDayPro {
+		t.Errorf("limits = %v, %v, want the limits of the pro subscription", bookProvider.MaxCallsPerSecond(), bookProvider.MaxCallsPerDay())
+	}
+	if configuredSubscriptionType != "pro" || configuredApiKey != "key" {
+		t.Errorf("client subscription = %q, %q, want the configured one", configuredSubscriptionType, configuredApiKey)
+	}
+}
+
+func TestBookDetailsWithPricesWithoutTheProSubscription(t *testing.T) {
+	Configure("basic", "key")
+	defer Configure("", "")
+
+	_, _, err := BookDetails(context.Background(), "9780261103344", true)
+	if err == nil {
+		t.Error("looked up prices with the basic subscription")
+	}
+}
@@ -0,0 +1,93 @@
+package openlibrary
+
+import (
+	"context"
+	"log"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/zaelmyth/book-data-collector/internal/client"
+)
+
+const defaultApiUrl = "https://openlibrary.org"
+const MaxPageSize = 100
+const coversUrl = "https://covers.openlibrary.org"
+
+// searchFields limits the search response to the fields that are used, otherwise every work comes with the keys of all
+// of its editions. Edition data is requested for the best matching edition only because the edition data of a work
+// covers all its editions.
+const searchFields = "key,title,subtitle,author_name,subject,ratings_average,ratings_count," +
+	"editions,editions.key,editions.title,editions.subtitle,editions.isbn,editions.publisher,editions.language," +
+	"editions.publish_date,editions.cover_i"
+
+func Search(ctx context.Context, parameters SearchParameters) (SearchResults, int, error) {
+	validateSearchParameters(parameters)
+
+	if parameters.Limit == 0 {
+		parameters.Limit = MaxPageSize
+	}
+
+	requestData := url.Values{
+		"page":   {strconv.Itoa(parameters.Page)},
+		"limit":  {strconv.Itoa(parameters.Limit)},
+		"fields": {searchFields},
+	}
+
+	if parameters.Title != "" {
+		requestData.Add("title", parameters.Title)
+	}
+
+	if parameters.Subject != "" {
+		requestData.Add("subject", parameters.Subject)
+	}
+
+	if parameters.Isbn != "" {
+		requestData.Add("isbn", parameters.Isbn)
+	}
+
+	return call(ctx, "get", "/search.json", requestData, SearchResults{})
+}
+
+// EditionByIsbn the api redirects isbn urls to the edition they belong to, the redirect is followed by the http client
+func EditionByIsbn(ctx context.Context, isbn string) (Edition, int, error) {
+	return call(ctx, "get", "/isbn/"+url.PathEscape(isbn)+".json", url.Values{}, Edition{})
+}
+
+func EditionDetails(ctx context.Context, key string) (Edition, int, error) {
+	return call(ctx, "get", "/books/"+url.PathEscape(strings.TrimPrefix(key, "/books/"))+".json", url.Values{}, Edition{})
+}
+
+func AuthorDetails(ctx context.Context, key string) (Author, int, error) {
+	return call(ctx, "get", "/authors/"+url.PathEscape(strings.TrimPrefix(key, "/authors/"))+".json", url.Values{}, Author{})
+}
+
+func CoverUrl(coverId int) string {
+	return coversUrl + "/b/id/" + strconv.Itoa(coverId) + "-L.jpg"
+}
+
+func call[T any](ctx context.Context, method string, url string, data url.Values, responseStruct T) (T, int, error) {
+	// todo: use config
+	apiUrl := os.Getenv("OPEN_LIBRARY_API_URL")
+	if apiUrl == "" {
+		apiUrl = defaultApiUrl
+	}
+
+	// the api asks clients to identify themselves so that heavy users can be contacted instead of blocked
+	return client.Call(ctx, method, strings.TrimSuffix(apiUrl, "/")+url, data, map[string]string{"User-Agent": "book-data-collector"}, responseStruct)
+}
+
+func validateSearchParameters(parameters SearchParameters) {
+	if parameters.Title == "" && parameters.Subject == "" && parameters.Isbn == "" {
+		log.Fatal("Empty query")
+	}
+
+	if parameters.Page < 1 {
+		log.Fatal("Page cannot be less than 1")
+	}
+
+	if parameters.Limit < 0 || parameters.Limit > MaxPageSize {
+		log.Fatal("Invalid limit parameter")
+	}
+}
@@ -0,0 +1,265 @@
+package openlibrary
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/zaelmyth/book-data-collector/internal/configuration"
+	"github.com/zaelmyth/book-data-collector/internal/isbn"
+	"github.com/zaelmyth/book-data-collector/internal/provider"
+)
+
+func init() {
+	provider.Register("openlibrary", NewProvider)
+}
+
+type Provider struct {
+	searchBy string
+	// authors are only referenced by key in editions so their names are cached to avoid looking them up every time
+	authors      map[string]string
+	authorsMutex *sync.Mutex
+}
+
+func NewProvider(config configuration.Config) provider.Provider {
+	return Provider{
+		searchBy:     config.SearchBy,
+		authors:      make(map[string]string),
+		authorsMutex: &sync.Mutex{},
+	}
+}
+
+func (p Provider) IdColumn() string {
+	return "open_library_id"
+}
+
+// IsbnBatchSize is 1 because isbns are looked up through the isbn endpoint which only accepts one isbn
+func (p Provider) IsbnBatchSize() int {
+	return 1
+}
+
+// MaxCallsPerSecond is the limit the api documents for clients that identify themselves with a user agent
+func (p Provider) MaxCallsPerSecond() int {
+	return 3
+}
+
+func (p Provider) MaxCallsPerDay() int {
+	return 0
+}
+
+func (p Provider) Search(ctx context.Context, query string, page int) (provider.Page, error) {
+	parameters := SearchParameters{
+		Page:  page,
+		Limit: MaxPageSize,
+	}
+
+	switch p.searchBy {
+	case "title":
+		parameters.Title = query
+	case "subject":
+		parameters.Subject = query
+	case "isbn":
+		parameters.Isbn = query
+	}
+
+	results, _, err := Search(ctx, parameters)
+
+	return provider.Page{
+		Number:  page,
+		Total:   results.NumFound,
+		Records: worksToRecords(results.Docs),
+	}, err
+}
+
+func (p Provider) SearchByIsbn(ctx context.Context, isbns []string) (provider.Page, error) {
+	if len(isbns) != 1 {
+		log.Fatal("Only one ISBN can be searched at a time")
+	}
+
+	edition, _, err := EditionByIsbn(ctx, isbns[0])
+	if err != nil || edition.Key == "" {
+		return provider.Page{Number: 1}, err
+	}
+
+	return provider.Page{
+		Number:  1,
+		Total:   1,
+		Records: []provider.Record{p.editionToRecord(ctx, edition)},
+	}, nil
+}
+
+// SearchById looks up the edition by its key, the id of a book is the key without the /books/ prefix
+func (p Provider) SearchById(ctx context.Context, ids []string) (provider.Page, error) {
+	if len(ids) != 1 {
+		log.Fatal("Only one id can be searched at a time")
+	}
+
+	edition, _, err := EditionDetails(ctx, ids[0])
+	if err != nil || edition.Key == "" {
+		return provider.Page{Number: 1}, err
+	}
+
+	return provider.Page{
+		Number:  1,
+		Total:   1,
+		Records: []provider.Record{p.editionToRecord(ctx, edition)},
+	}, nil
+}
+
+func (p Provider) NextPage(page provider.Page) (int, bool) {
+	return provider.NextPageByTotal(page, MaxPageSize)
+}
+
+// Decode maps the search, isbn and edition endpoints, the authors of editions are looked up with the client so they come from
+// the archive when it is replayed
+func (p Provider) Decode(ctx context.Context, endpoint string, body []byte) ([]provider.Record, error) {
+	switch {
+	case strings.HasSuffix(endpoint, "/search.json"):
+		var results SearchResults
+		err := json.Unmarshal(body, &results)
+		return worksToRecords(results.Docs), err
+	case strings.Contains(endpoint, "/isbn/"), strings.Contains(endpoint, "/books/"):
+		var edition Edition
+		err := json.Unmarshal(body, &edition)
+		if err != nil || edition.Key == "" {
+			return nil, err
+		}
+		return []provider.Record{p.editionToRecord(ctx, edition)}, nil
+	default:
+		return nil, nil
+	}
+}
+
+func (p Provider) authorName(ctx context.Context, key string) (string, bool) {
+	p.authorsMutex.Lock()
+	name, isCached := p.authors[key]
+	p.authorsMutex.Unlock()
+
+	if isCached {
+		return name, true
+	}
+
+	author, _, err := AuthorDetails(ctx, key)
+	if err != nil {
+		// the edition is still worth saving without the author so the error is only logged
+		log.Println(err)
+		return "", false
+	}
+
+	if author.Name == "" {
+		return "", false
+	}
+
+	p.authorsMutex.Lock()
+	p.authors[key] = author.Name
+	p.authorsMutex.Unlock()
+
+	return author.Name, true
+}
+
+// worksToRecords skips the works that have no edition in the results because a record is always an edition
+func worksToRecords(works []Work) []provider.Record {
+	records := make([]provider.Record, 0, len(works))
+	for _, work := range works {
+		record, hasEdition := workToRecord(work)
+		if hasEdition {
+			records = append(records, record)
+		}
+	}
+
+	return records
+}
+
+// workToRecord maps a work to the edition that matches the search best. The edition data is taken from that edition
+// only, the authors, subjects and ratings are the ones of the work.
+func workToRecord(work Work) (provider.Record, bool) {
+	if len(work.Editions.Docs) == 0 {
+		return provider.Record{}, false
+	}
+
+	edition := work.Editions.Docs[0]
+	id := strings.TrimPrefix(edition.Key, "/books/")
+	if id == "" {
+		return provider.Record{}, false
+	}
+
+	record := provider.Record{
+		Id:            id,
+		OpenLibraryId: id,
+		Title:         edition.Title,
+		Subtitle:      edition.Subtitle,
+		AverageRating: work.RatingsAverage,
+		RatingCount:   work.RatingsCount,
+		Authors:       work.AuthorName,
+		Subjects:      work.Subject,
+	}
+
+	if record.Title == "" {
+		record.Title = work.Title
+		record.Subtitle = work.Subtitle
+	}
+
+	record.Isbn13, record.Isbn = isbn.Normalize(edition.Isbn...)
+
+	if len(edition.Publisher) > 0 {
+		record.Publisher = edition.Publisher[0]
+	}
+
+	if len(edition.Language) > 0 {
+		record.Language = edition.Language[0]
+	}
+
+	if len(edition.PublishDate) > 0 {
+		record.DatePublished = edition.PublishDate[0]
+	}
+
+	if edition.CoverI > 0 {
+		record.Image = CoverUrl(edition.CoverI)
+	}
+
+	return record, true
+}
+
+func (p Provider) editionToRecord(ctx context.Context, edition Edition) provider.Record {
+	record := provider.Record{
+		Id:            strings.TrimPrefix(edition.Key, "/books/"),
+		OpenLibraryId: strings.TrimPrefix(edition.Key, "/books/"),
+		Title:         edition.Title,
+		TitleLong:     edition.FullTitle,
+		Subtitle:      edition.Subtitle,
+		DeweyDecimal:  strings.Join(edition.DeweyDecimalClass, ", "),
+		Binding:       edition.PhysicalFormat,
+		DatePublished: edition.PublishDate,
+		Edition:       edition.EditionName,
+		Pages:         edition.NumberOfPages,
+		Dimensions:    edition.PhysicalDimensions,
+		Synopsis:      string(edition.Description),
+		Subjects:      edition.Subjects,
+	}
+
+	record.Isbn13, record.Isbn = isbn.Normalize(slices.Concat(edition.Isbn13, edition.Isbn10)...)
+
+	if len(edition.Publishers) > 0 {
+		record.Publisher = edition.Publishers[0]
+	}
+
+	if len(edition.Languages) > 0 {
+		record.Language = strings.TrimPrefix(edition.Languages[0].Key, "/languages/")
+	}
+
+	if len(edition.Covers) > 0 && edition.Covers[0] > 0 {
+		record.Image = CoverUrl(edition.Covers[0])
+	}
+
+	for _, author := range edition.Authors {
+		name, isFound := p.authorName(ctx, author.Key)
+		if isFound {
+			record.Authors = append(record.Authors, name)
+		}
+	}
+
+	return record
+}
@@ -0,0 +1,112 @@
+package openlibrary
+
+import (
+	"testing"
+)
+
+func TestWorkToRecord(t *testing.T) {
+	tests := []struct {
+		name           string
+		work           Work
+		wantEdition    bool
+		wantId         string
+		wantTitle      string
+		wantIsbn13     string
+		wantIsbn       string
+		wantPublisher  string
+		wantLanguage   string
+		wantPublished  string
+		wantAuthorName string
+	}{
+		{
+			name: "data of the best matching edition",
+			work: Work{
+				Key:        "/works/OL1W",
+				Title:      "The Hobbit",
+				AuthorName: []string{"J. R. R. Tolkien"},
+				Editions: WorkEditions{NumFound: 1, Docs: []WorkEdition{{
+					Key:         "/books/OL3M",
+					Title:       "The Hobbit, or There and Back Again",
+					Isbn:        []string{"0-306-40615-2"},
+					Publisher:   []string{"Allen & Unwin"},
+					Language:    []string{"eng"},
+					PublishDate: []string{"1937"},
+				}}},
+			},
+			wantEdition:    true,
+			wantId:         "OL3M",
+			wantTitle:      "The Hobbit, or There and Back Again",
+			wantIsbn13:     "9780306406157",
+			wantIsbn:       "0306406152",
+			wantPublisher:  "Allen & Unwin",
+			wantLanguage:   "eng",
+			wantPublished:  "1937",
+			wantAuthorName: "J. R. R. Tolkien",
+		},
+		{
+			name: "edition without a title or isbns",
+			work: Work{
+				Key:      "/works/OL1W",
+				Title:    "The Hobbit",
+				Editions: WorkEditions{NumFound: 1, Docs: []WorkEdition{{Key: "/books/OL3M"}}},
+			},
+			wantEdition: true,
+			wantId:      "OL3M",
+			wantTitle:   "The Hobbit",
+		},
+		{
+			name:        "work without editions is skipped",
+			work:        Work{Key: "/works/OL1W", Title: "The Hobbit"},
+			wantEdition: false,
+		},
+		{
+			name: "edition without a key is skipped",
+			work: Work{
+				Key:      "/works/OL1W",
+				Editions: WorkEditions{NumFound: 1, Docs: []WorkEdition{{Isbn: []string{"0306406152"}}}},
+			},
+			wantEdition: false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			record, hasEdition := workToRecord(test.work)
+
+			if hasEdition != test.wantEdition {
+				t.Fatalf("has edition = %v, want %v", hasEdition, test.wantEdition)
+			}
+			if !hasEdition {
+				return
+			}
+
+			if record.Id != test.wantId || record.OpenLibraryId != test.wantId {
+				t.Errorf("id = %q, open library id = %q, want %q", record.Id, record.OpenLibraryId, test.wantId)
+			}
+			if record.Title != test.wantTitle {
+				t.Errorf("title = %q, want %q", record.Title, test.wantTitle)
+			}
+			if record.Isbn13 != test.wantIsbn13 || record.Isbn != test.wantIsbn {
+				t.Errorf("isbns = %q, %q, want %q, %q", record.Isbn13, record.Isbn, test.wantIsbn13, test.wantIsbn)
+			}
+			if record.Publisher != test.wantPublisher || record.Language != test.wantLanguage || record.DatePublished != test.wantPublished {
+				t.Errorf("publisher, language and date = %q, %q, %q, want %q, %q, %q", record.Publisher, record.Language, record.DatePublished, test.wantPublisher, test.wantLanguage, test.wantPublished)
+			}
+			if test.wantAuthorName != "" && (len(record.Authors) != 1 || record.Authors[0] != test.wantAuthorName) {
+				t.Errorf("authors = %q, want the authors of the work", record.Authors)
+			}
+		})
+	}
+}
+
+func TestWorksToRecordsSkipsWorksWithoutEditions(t *testing.T) {
+	works := []Work{
+		{Key: "/works/OL1W"},
+		{Key: "/works/OL2W", Editions: WorkEditions{NumFound: 1, Docs: []WorkEdition{{Key: "/books/OL2M"}}}},
+	}
+
+	records := worksToRecords(works)
+	if len(records) != 1 || records[0].OpenLibraryId != "OL2M" {
+		t.Errorf("records = %+v, want only the edition OL2M", records)
+	}
+}
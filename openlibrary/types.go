@@ -0,0 +1,114 @@
+package openlibrary
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+/* Types returned by the client */
+
+type SearchResults struct {
+	NumFound int
+	Start    int
+	Docs     []Work
+}
+
+// Work is a search result. The search api returns works, which group all the editions of a book, so edition specific
+// data like publishers are lists that cover all of them. Editions has the edition that matches the search best.
+type Work struct {
+	Key            string
+	Title          string
+	Subtitle       string
+	AuthorName     []string `json:"author_name"`
+	Subject        []string
+	RatingsAverage float64 `json:"ratings_average"`
+	RatingsCount   int     `json:"ratings_count"`
+	Editions       WorkEditions
+}
+
+type WorkEditions struct {
+	NumFound int
+	Docs     []WorkEdition
+}
+
+// WorkEdition is an edition of a search result with only the fields that are requested for it
+type WorkEdition struct {
+	Key         string
+	Title       string
+	Subtitle    string
+	Isbn        []string
+	Publisher   []string
+	Language    []string
+	PublishDate []string `json:"publish_date"`
+	CoverI      int      `json:"cover_i"`
+}
+
+type Edition struct {
+	Key                string
+	Title              string
+	Subtitle           string
+	FullTitle          string `json:"full_title"`
+	Authors            []Reference
+	Publishers         []string
+	PublishDate        string   `json:"publish_date"`
+	EditionName        string   `json:"edition_name"`
+	NumberOfPages      int      `json:"number_of_pages"`
+	PhysicalFormat     string   `json:"physical_format"`
+	PhysicalDimensions string   `json:"physical_dimensions"`
+	Isbn10             []string `json:"isbn_10"`
+	Isbn13             []string `json:"isbn_13"`
+	Languages          []Reference
+	Subjects           []string
+	Description        Text
+	Covers             []int
+	DeweyDecimalClass  []string `json:"dewey_decimal_class"`
+	Works              []Reference
+}
+
+type Author struct {
+	Key          string
+	Name         string
+	PersonalName string `json:"personal_name"`
+}
+
+/* Types used by the types above */
+
+type Reference struct {
+	Key string
+}
+
+type Text string
+
+// UnmarshalJSON is overridden because the api response for text fields can be a string or an object with a value
+// so we have to convert it
+func (f *Text) UnmarshalJSON(data []byte) error {
+	var text interface{}
+
+	err := json.Unmarshal(data, &text)
+	if err != nil {
+		return err
+	}
+
+	if object, isObject := text.(map[string]interface{}); isObject {
+		text = object["value"]
+	}
+
+	if text == nil {
+		*f = ""
+		return nil
+	}
+
+	*f = Text(fmt.Sprint(text))
+
+	return nil
+}
+
+/* Request types */
+
+type SearchParameters struct {
+	Title   string
+	Subject string
+	Isbn    string
+	Page    int
+	Limit   int
+}